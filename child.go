@@ -0,0 +1,138 @@
+package tcc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const childActionName = "tcc-child"
+
+// ChildAction is an action wrapping a child tcc, which can be on the same or another engine.
+// Try of the ChildAction runs the Try phase of the child tcc, Confirm confirms the child tcc,
+// and Cancel cancels it. The timeout of the child tcc should be longer than the parent's,
+// otherwise the child tcc may be canceled before the parent confirms.
+type ChildAction struct {
+	Table   string // mq table name of the child engine
+	Queue   string // mq name of the child engine
+	Id      int64  // id of the child tcc
+	actions []Action
+}
+
+type tccRef struct {
+	Table string
+	Queue string
+	Id    int64
+}
+
+// NewChild creates a child tcc, which is tried by trying the returned ChildAction on a parent tcc.
+func (engine *Engine) NewChild(timeout time.Duration, concurrent bool, actions ...Action) (
+	*ChildAction, error,
+) {
+	tcc, err := engine.New(timeout, concurrent)
+	if err != nil {
+		return nil, err
+	}
+	return &ChildAction{
+		Table: engine.mqTableName, Queue: engine.mqName, Id: tcc.msg.Id, actions: actions,
+	}, nil
+}
+
+func (child *ChildAction) Name() string {
+	return childActionName
+}
+
+func (child *ChildAction) Try() error {
+	return child.TryContext(context.Background())
+}
+
+func (child *ChildAction) Confirm() error {
+	return child.ConfirmContext(context.Background())
+}
+
+func (child *ChildAction) Cancel() error {
+	return child.CancelContext(context.Background())
+}
+
+func (child *ChildAction) TryContext(ctx context.Context) error {
+	tcc, err := child.tcc()
+	if err != nil {
+		return err
+	}
+	// the child's Trys see the cancellation, deadline and metadata of the parent's context.
+	tcc.ctx = ctx
+	if parent := FromContext(ctx); parent != nil {
+		if err := tcc.setParent(parent); err != nil {
+			return err
+		}
+	}
+	for _, action := range child.actions {
+		if err := tcc.Try(action); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (child *ChildAction) ConfirmContext(ctx context.Context) error {
+	tcc, err := child.tcc()
+	if err != nil {
+		return err
+	}
+	if tcc.Status() == statusConfirmed {
+		return nil
+	}
+	return tcc.Confirm()
+}
+
+func (child *ChildAction) CancelContext(ctx context.Context) error {
+	tcc, err := child.tcc()
+	if err != nil {
+		return err
+	}
+	if tcc.Status() == statusCanceled {
+		return nil
+	}
+	return tcc.Cancel()
+}
+
+func (child *ChildAction) tcc() (*TCC, error) {
+	engine := getEngine(child.Table, child.Queue)
+	if engine == nil {
+		return nil, fmt.Errorf("tcc engine %s of table %s not exists", child.Queue, child.Table)
+	}
+	return engine.Get(child.Id)
+}
+
+func (tcc *TCC) setParent(parent *TCC) error {
+	ref, err := json.Marshal(tccRef{
+		Table: parent.engine.mqTableName, Queue: parent.engine.mqName, Id: parent.msg.Id,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = tcc.update(
+		`data = jsonb_set(data, '{Parent}'::text[], `+quote(string(ref))+`::jsonb)`,
+		statusTrying, "set Parent", nil,
+	)
+	return err
+}
+
+var engines = struct {
+	m     map[string]*Engine
+	mutex sync.RWMutex
+}{m: make(map[string]*Engine)}
+
+func addEngine(engine *Engine) {
+	engines.mutex.Lock()
+	defer engines.mutex.Unlock()
+	engines.m[engine.mqTableName+" "+engine.mqName] = engine
+}
+
+func getEngine(table, queue string) *Engine {
+	engines.mutex.RLock()
+	defer engines.mutex.RUnlock()
+	return engines.m[table+" "+queue]
+}
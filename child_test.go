@@ -0,0 +1,81 @@
+package tcc
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+func ExampleChildAction() {
+	child, err := tccEngine.NewChild(2*time.Minute, false, testAction1{}, testAction2{})
+	if err != nil {
+		panic(err)
+	}
+	parent, err := tccEngine.New(time.Minute, false)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(parent.Try(child))
+	confirmErr := parent.Confirm()
+	parentStatus, _ := parent.Wait(context.Background())
+	childStatus, _ := tccEngine.Wait(context.Background(), child.Id)
+	childTCC, err := tccEngine.Get(child.Id)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(confirmErr, parentStatus, childStatus, childTCC.msg.Data.(*tccData).Parent.Id == parent.Id())
+	// Output:
+	// action1 Try
+	// action2 Try
+	// <nil>
+	// action1 Confirm
+	// action2 Confirm
+	// <nil> confirmed confirmed true
+}
+
+func ExampleChildAction_context() {
+	child, err := tccEngine.NewChild(2*time.Minute, false, testMetadataAction{})
+	if err != nil {
+		panic(err)
+	}
+	parent, err := tccEngine.New(time.Minute, false, WithMetadata(map[string]string{"tenant": "t1"}))
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(parent.Try(child))
+	fmt.Println(parent.Cancel())
+	fmt.Println(parent.Wait(context.Background()))
+	// Output:
+	// metadata-action Try t1
+	// <nil>
+	// <nil>
+	// canceled <nil>
+}
+
+// testMetadataAction prints the tenant in the metadata of its context on Try.
+type testMetadataAction struct {
+}
+
+func (ta testMetadataAction) Name() string {
+	return "metadata-action"
+}
+func (ta testMetadataAction) Try() error {
+	return ta.TryContext(context.Background())
+}
+func (ta testMetadataAction) Confirm() error {
+	return nil
+}
+func (ta testMetadataAction) Cancel() error {
+	return nil
+}
+func (ta testMetadataAction) TryContext(ctx context.Context) error {
+	fmt.Println("metadata-action Try", MetadataFromContext(ctx)["tenant"])
+	return nil
+}
+
+func ExampleChildAction_Cancel() {
+	child := &ChildAction{Table: "sqlmq", Queue: "tcc-none", Id: 1}
+	fmt.Println(child.Cancel())
+	// Output:
+	// tcc engine tcc-none of table sqlmq not exists
+}
@@ -0,0 +1,144 @@
+package tcc
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Codec marshals and unmarshals the payloads of actions.
+// "json"(the default) and "gob" are built in, others(msgpack, protobuf, etc.) can be added by RegisterCodec.
+type Codec interface {
+	Name() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// CodecAction is an action whose payload is marshaled by the codec of the name instead of json.
+type CodecAction interface {
+	Codec() string
+}
+
+// Marshaler is an action which controls exactly what is persisted for it, such as only ids.
+// It takes precedence over CodecAction.
+type Marshaler interface {
+	MarshalTCC() ([]byte, error)
+}
+
+// Unmarshaler is an action which restores itself from the data of MarshalTCC,
+// such as refetching the rest by ids.
+type Unmarshaler interface {
+	UnmarshalTCC([]byte) error
+}
+
+const codecJSON = "json"
+const codecTCC = "tcc"
+
+var codecs = map[string]Codec{
+	codecJSON: jsonCodec{},
+	"gob":     gobCodec{},
+	codecTCC:  tccCodec{},
+}
+var codecsMutex sync.RWMutex
+
+// RegisterCodec registers a codec to be selected by actions.
+func RegisterCodec(codec Codec) {
+	codecsMutex.Lock()
+	defer codecsMutex.Unlock()
+	if _, ok := codecs[codec.Name()]; ok {
+		panic(time.Now().Format(time.RFC3339Nano) + " codec " + codec.Name() + " already registered")
+	}
+	codecs[codec.Name()] = codec
+}
+
+var errCodecNotRegistered = errors.New("codec not registered")
+
+// the codec of the name, the empty name means json, which is for the actions recorded before codecs.
+func getCodec(name string) (Codec, error) {
+	if name == "" {
+		name = codecJSON
+	}
+	codecsMutex.RLock()
+	codec, ok := codecs[name]
+	codecsMutex.RUnlock()
+	if !ok {
+		return nil, errors.New(name + ": " + errCodecNotRegistered.Error())
+	}
+	return codec, nil
+}
+
+func codecOf(action Action) string {
+	if _, ok := actionValue(action).(Marshaler); ok {
+		return codecTCC
+	}
+	if a, ok := actionValue(action).(CodecAction); ok && a.Codec() != "" {
+		return a.Codec()
+	}
+	return codecJSON
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string {
+	return codecJSON
+}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+type gobCodec struct{}
+
+func (gobCodec) Name() string {
+	return "gob"
+}
+
+func (gobCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// tccCodec calls the MarshalTCC and UnmarshalTCC methods of actions.
+type tccCodec struct{}
+
+func (tccCodec) Name() string {
+	return codecTCC
+}
+
+func (tccCodec) Marshal(v interface{}) ([]byte, error) {
+	if m, ok := v.(Marshaler); ok {
+		return m.MarshalTCC()
+	}
+	return nil, errors.New("MarshalTCC not implemented")
+}
+
+// v is a pointer to the action, and the action itself may be a pointer.
+func (tccCodec) Unmarshal(data []byte, v interface{}) error {
+	if _, ok := v.(Unmarshaler); !ok {
+		if elem := reflect.ValueOf(v).Elem(); elem.Kind() == reflect.Ptr {
+			if elem.IsNil() {
+				elem.Set(reflect.New(elem.Type().Elem()))
+			}
+			v = elem.Interface()
+		}
+	}
+	if u, ok := v.(Unmarshaler); ok {
+		return u.UnmarshalTCC(data)
+	}
+	return errors.New("UnmarshalTCC not implemented")
+}
@@ -0,0 +1,107 @@
+package tcc
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+func ExampleRegisterCodec() {
+	tcc, err := tccEngine.New(time.Minute, false)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(tcc.Try(testGobAction{Amount: 1<<62 + 1}))
+	fmt.Println(tcc.Confirm())
+	fmt.Println(tcc.Wait(context.Background()))
+
+	defer func() {
+		fmt.Println(timePrefix.ReplaceAllString(recover().(string), ""))
+	}()
+	RegisterCodec(gobCodec{})
+	// Output:
+	// <nil>
+	// <nil>
+	// gob-action Confirm 4611686018427387905
+	// confirmed <nil>
+	// codec gob already registered
+}
+
+type testGobAction struct {
+	Amount int64
+}
+
+func (ta testGobAction) Name() string {
+	return "gob-action"
+}
+func (ta testGobAction) Codec() string {
+	return "gob"
+}
+func (ta testGobAction) Try() error {
+	return nil
+}
+func (ta testGobAction) Confirm() error {
+	fmt.Println("gob-action Confirm", ta.Amount)
+	return nil
+}
+func (ta testGobAction) Cancel() error {
+	return nil
+}
+
+func ExampleMarshaler() {
+	testOrders[7] = "order 7"
+	tcc, err := tccEngine.New(time.Minute, false)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(tcc.Try(&testMarshalerAction{Id: 7, Title: "order 7"}))
+	var data []byte
+	if err := testDB.QueryRow(
+		`SELECT data->'Actions'->0 FROM sqlmq WHERE id = $1`, tcc.Id(),
+	).Scan(&data); err != nil {
+		panic(err)
+	}
+	fmt.Println(string(data))
+	testOrders[7] = "order 7 refetched"
+	fmt.Println(tcc.Confirm())
+	fmt.Println(tcc.Wait(context.Background()))
+	// Output:
+	// <nil>
+	// {"Raw": "Nw==", "Name": "marshaler-action", "Codec": "tcc"}
+	// <nil>
+	// marshaler-action Confirm 7 order 7 refetched
+	// confirmed <nil>
+}
+
+var testOrders = map[int]string{}
+
+// testMarshalerAction persists only its id, and refetches the title by the id.
+type testMarshalerAction struct {
+	Id    int
+	Title string
+}
+
+func (ta *testMarshalerAction) Name() string {
+	return "marshaler-action"
+}
+func (ta *testMarshalerAction) MarshalTCC() ([]byte, error) {
+	return []byte(strconv.Itoa(ta.Id)), nil
+}
+func (ta *testMarshalerAction) UnmarshalTCC(data []byte) (err error) {
+	if ta.Id, err = strconv.Atoi(string(data)); err != nil {
+		return err
+	}
+	ta.Title = testOrders[ta.Id]
+	return nil
+}
+func (ta *testMarshalerAction) Try() error {
+	return nil
+}
+func (ta *testMarshalerAction) Confirm() error {
+	fmt.Println("marshaler-action Confirm", ta.Id, ta.Title)
+	return nil
+}
+func (ta *testMarshalerAction) Cancel() error {
+	return nil
+}
@@ -0,0 +1,79 @@
+package tcc
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const statusConfirmFailed = "confirm-failed"
+
+var setConfirmFailed = fmt.Sprintf(setTCCStatus, statusConfirmFailed)
+
+type permanentError struct {
+	err error
+}
+
+// Permanent marks the error returned by Confirm of an action as permanent, which means the Confirm
+// can never succeed. It takes effect only if Engine.CancelOnConfirmFailure is true.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err}
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e permanentError) Unwrap() error {
+	return e.err
+}
+
+func isPermanent(err error) bool {
+	var e permanentError
+	return errors.As(err, &e)
+}
+
+// if the confirm of the tcc should be given up.
+func (tcc *TCC) confirmGivenUp(err error) bool {
+	if !tcc.engine.CancelOnConfirmFailure {
+		return false
+	}
+	return isPermanent(err) ||
+		tcc.engine.MaxConfirmTries > 0 && tcc.msg.TriedCount+1 >= tcc.engine.MaxConfirmTries
+}
+
+// give up the confirm of the tcc: record it as confirm-failed, and compensate it.
+func (tcc *TCC) escalateConfirmFailure(data *tccData, tx *sql.Tx) (time.Duration, bool, error) {
+	if canCommit, err := tcc.update(setConfirmFailed, statusConfirmed, "give up Confirm", tx); err != nil {
+		return 0, canCommit, err
+	}
+	data.Status = statusConfirmFailed
+	return tcc.compensate(data, tx)
+}
+
+// compensate a confirm-failed tcc in reverse order:
+// revert the confirmed actions, and cancel the unconfirmed ones.
+func (tcc *TCC) compensate(data *tccData, tx *sql.Tx) (time.Duration, bool, error) {
+	for i := len(data.Actions) - 1; i >= 0; i-- {
+		action := data.Actions[i]
+		var retryAfter time.Duration
+		var canCommit bool
+		var err error
+		switch action.Status {
+		case statusCanceled, statusReverted:
+			continue
+		case statusConfirmed:
+			retryAfter, canCommit, err = action.revert(tcc, tx, i)
+		default:
+			retryAfter, canCommit, err = action.cancel(tcc, tx, i)
+		}
+		if err != nil {
+			return retryAfter, canCommit, errors.New(action.Name + ": " + err.Error())
+		}
+	}
+	return 0, true, nil
+}
@@ -0,0 +1,70 @@
+package tcc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+func ExampleEngine_CancelOnConfirmFailure() {
+	tccEngine.CancelOnConfirmFailure = true
+	defer func() {
+		tccEngine.CancelOnConfirmFailure = false
+	}()
+	tcc, err := tccEngine.New(time.Minute, false)
+	if err != nil {
+		panic(err)
+	}
+	for _, action := range []Action{
+		testConfirmFailAction{N: 1}, testConfirmFailAction{N: 2, Fail: true}, testConfirmFailAction{N: 3},
+	} {
+		if err := tcc.Try(action); err != nil {
+			panic(err)
+		}
+	}
+	fmt.Println(tcc.Confirm())
+	fmt.Println(tcc.Wait(context.Background()))
+	// Output:
+	// <nil>
+	// confirm-fail 1 Confirm
+	// confirm-fail 2 Confirm
+	// confirm-fail 3 Cancel
+	// confirm-fail 2 Cancel
+	// confirm-fail 1 Revert
+	// confirm-failed <nil>
+}
+
+func ExamplePermanent() {
+	err := fmt.Errorf("action: %w", Permanent(errors.New("expired")))
+	fmt.Println(err, isPermanent(err), isPermanent(errors.New("expired")), Permanent(nil))
+	// Output:
+	// action: expired true false <nil>
+}
+
+type testConfirmFailAction struct {
+	N    int
+	Fail bool
+}
+
+func (ta testConfirmFailAction) Name() string {
+	return "confirm-fail-action"
+}
+func (ta testConfirmFailAction) Try() error {
+	return nil
+}
+func (ta testConfirmFailAction) Confirm() error {
+	fmt.Printf("confirm-fail %d Confirm\n", ta.N)
+	if ta.Fail {
+		return Permanent(errors.New("expired"))
+	}
+	return nil
+}
+func (ta testConfirmFailAction) Cancel() error {
+	fmt.Printf("confirm-fail %d Cancel\n", ta.N)
+	return nil
+}
+func (ta testConfirmFailAction) Revert() error {
+	fmt.Printf("confirm-fail %d Revert\n", ta.N)
+	return nil
+}
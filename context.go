@@ -0,0 +1,52 @@
+package tcc
+
+import (
+	"context"
+	"errors"
+)
+
+type tccKey struct{}
+
+type metadataKey struct{}
+
+// ErrNoTCC is returned by TryCtx if there is no tcc in the context.
+var ErrNoTCC = errors.New("no tcc in the context")
+
+// WithTCC returns a copy of ctx carrying the tcc, so nested code can Try actions on it by TryCtx.
+// The context passed to ContextAction.TryContext carries the tcc already.
+func WithTCC(ctx context.Context, tcc *TCC) context.Context {
+	return context.WithValue(ctx, tccKey{}, tcc)
+}
+
+// FromContext returns the tcc carried by ctx, or nil if there is none.
+func FromContext(ctx context.Context) *TCC {
+	tcc, _ := ctx.Value(tccKey{}).(*TCC)
+	return tcc
+}
+
+// TryCtx tries the action on the tcc carried by ctx.
+func TryCtx(ctx context.Context, action Action) error {
+	tcc := FromContext(ctx)
+	if tcc == nil {
+		return ErrNoTCC
+	}
+	return tcc.Try(action)
+}
+
+// the context passed to actions.
+func (tcc *TCC) context() context.Context {
+	ctx := tcc.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if metadata := tcc.Metadata(); len(metadata) > 0 {
+		ctx = context.WithValue(ctx, metadataKey{}, metadata)
+	}
+	return ctx
+}
+
+// MetadataFromContext returns the metadata of the tcc from the context passed to a ContextAction.
+func MetadataFromContext(ctx context.Context) map[string]string {
+	metadata, _ := ctx.Value(metadataKey{}).(map[string]string)
+	return metadata
+}
@@ -0,0 +1,29 @@
+package tcc
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+func ExampleTryCtx() {
+	fmt.Println(TryCtx(context.Background(), testAction1{}))
+
+	tcc, err := tccEngine.New(time.Minute, false)
+	if err != nil {
+		panic(err)
+	}
+	ctx := WithTCC(context.Background(), tcc)
+	fmt.Println(FromContext(ctx) == tcc)
+	fmt.Println(TryCtx(ctx, testAction1{}))
+	fmt.Println(tcc.Cancel())
+	fmt.Println(tcc.Wait(context.Background()))
+	// Output:
+	// no tcc in the context
+	// true
+	// action1 Try
+	// <nil>
+	// <nil>
+	// action1 Cancel
+	// canceled <nil>
+}
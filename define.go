@@ -0,0 +1,100 @@
+package tcc
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Definition is an action with a typed payload, defined by Define.
+type Definition[T any] struct {
+	name                 string
+	version              int
+	try, confirm, cancel func(ctx context.Context, payload *T) error
+}
+
+// Define an action with a typed payload, so no struct with four methods needs to be written.
+// A nil func does nothing. The payload is marshaled as the action, and unmarshaled back in the
+// mq handler. Define doesn't register the action, because no engine is given, so the Definition
+// must be registered to each engine by its Register method before its actions are tried.
+func Define[T any](
+	name string, try, confirm, cancel func(ctx context.Context, payload *T) error,
+) *Definition[T] {
+	return &Definition[T]{name: name, try: try, confirm: confirm, cancel: cancel}
+}
+
+func (def *Definition[T]) Name() string {
+	return def.name
+}
+
+// WithVersion sets the version of the payload(see VersionedAction), it must be called before Register.
+// The upgrades from older versions are registered by Engine.RegisterUpgrade with the name.
+func (def *Definition[T]) WithVersion(version int) *Definition[T] {
+	def.version = version
+	return def
+}
+
+// Register the definition to the engine.
+func (def *Definition[T]) Register(engine *Engine) {
+	engine.RegisterFuncVersion(def.name, def.version, def.decode)
+}
+
+// Action returns the action of the payload, to Try on a tcc.
+func (def *Definition[T]) Action(payload T) Action {
+	return typedAction[T]{def: def, payload: &payload}
+}
+
+func (def *Definition[T]) decode(raw []byte) (Action, error) {
+	var payload T
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return nil, err
+	}
+	return typedAction[T]{def: def, payload: &payload}, nil
+}
+
+type typedAction[T any] struct {
+	def     *Definition[T]
+	payload *T
+}
+
+func (ta typedAction[T]) Name() string {
+	return ta.def.name
+}
+
+func (ta typedAction[T]) Version() int {
+	return ta.def.version
+}
+
+func (ta typedAction[T]) Try() error {
+	return ta.TryContext(context.Background())
+}
+
+func (ta typedAction[T]) Confirm() error {
+	return ta.ConfirmContext(context.Background())
+}
+
+func (ta typedAction[T]) Cancel() error {
+	return ta.CancelContext(context.Background())
+}
+
+func (ta typedAction[T]) TryContext(ctx context.Context) error {
+	return ta.call(ctx, ta.def.try)
+}
+
+func (ta typedAction[T]) ConfirmContext(ctx context.Context) error {
+	return ta.call(ctx, ta.def.confirm)
+}
+
+func (ta typedAction[T]) CancelContext(ctx context.Context) error {
+	return ta.call(ctx, ta.def.cancel)
+}
+
+func (ta typedAction[T]) call(ctx context.Context, fn func(context.Context, *T) error) error {
+	if fn == nil {
+		return nil
+	}
+	return fn(ctx, ta.payload)
+}
+
+func (ta typedAction[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(ta.payload)
+}
@@ -0,0 +1,86 @@
+package tcc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+type testTransfer struct {
+	From, To string
+	Amount   int
+}
+
+var testTransferDef = Define("transfer",
+	func(ctx context.Context, t *testTransfer) error {
+		fmt.Printf("transfer Try %s->%s %d\n", t.From, t.To, t.Amount)
+		return nil
+	},
+	func(ctx context.Context, t *testTransfer) error {
+		fmt.Printf("transfer Confirm %s->%s %d\n", t.From, t.To, t.Amount)
+		return nil
+	},
+	nil,
+)
+
+func ExampleDefine() {
+	tcc, err := tccEngine.New(time.Minute, false)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(tcc.Try(testTransferDef.Action(testTransfer{From: "a", To: "b", Amount: 10})))
+	fmt.Println(tcc.Confirm())
+	fmt.Println(tcc.Wait(context.Background()))
+	// Output:
+	// transfer Try a->b 10
+	// <nil>
+	// <nil>
+	// transfer Confirm a->b 10
+	// confirmed <nil>
+}
+
+func ExampleDefinition_WithVersion() {
+	// version 1 of the payload renamed "Amt" of version 0 to "Amount".
+	def := Define("transfer-v1", nil,
+		func(ctx context.Context, t *testTransfer) error {
+			fmt.Printf("transfer-v1 Confirm %s->%s %d\n", t.From, t.To, t.Amount)
+			return nil
+		},
+		nil,
+	).WithVersion(1)
+	def.Register(tccEngine)
+	tccEngine.RegisterUpgrade("transfer-v1", 0, func(payload []byte) ([]byte, error) {
+		var v0 struct {
+			From, To string
+			Amt      int
+		}
+		if err := json.Unmarshal(payload, &v0); err != nil {
+			return nil, err
+		}
+		return json.Marshal(testTransfer{From: v0.From, To: v0.To, Amount: v0.Amt})
+	})
+
+	action, err := tccEngine.unmarshalAction(
+		tccAction{Name: "transfer-v1", Raw: []byte(`{"From": "a", "To": "b", "Amt": 10}`)},
+	)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(action.(VersionedAction).Version(), action.Confirm())
+
+	tcc, err := tccEngine.New(time.Minute, false)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(tcc.Try(def.Action(testTransfer{From: "c", To: "d", Amount: 20})))
+	fmt.Println(tcc.Confirm())
+	fmt.Println(tcc.Wait(context.Background()))
+	// Output:
+	// transfer-v1 Confirm a->b 10
+	// 1 <nil>
+	// <nil>
+	// <nil>
+	// transfer-v1 Confirm c->d 20
+	// confirmed <nil>
+}
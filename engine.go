@@ -17,8 +17,32 @@ type Engine struct {
 	sqlmq       *sqlmq.SqlMQ
 	mqName      string
 	mqTableName string
-	actions     map[string]Action
+	actions     map[string]registeredAction
+	upgrades    map[string]map[int]func(payload []byte) ([]byte, error)
 	mutex       sync.RWMutex
+
+	// XidGenerator generates the Xid of tccs, which is stored alongside the numeric id.
+	// Xid is not generated if it's nil.
+	XidGenerator func() (string, error)
+	// Lease is the lease of a tcc held by the process which created it, while the tcc is trying.
+	// The lease is renewed by heartbeat, if it lapses(the process crashed), the tcc is canceled at once
+	// instead of waiting for the Try phase timeout. Lease is disabled if it's zero.
+	Lease time.Duration
+	// MaxLifetime limits how far TCC.Extend can push the Try phase timeout, counting from creation.
+	// No limit if it's zero.
+	MaxLifetime time.Duration
+	// CancelOnConfirmFailure enables giving up the confirm of a tcc if the Confirm of an action fails
+	// permanently(see Permanent and MaxConfirmTries): the tcc is recorded as "confirm-failed",
+	// the unconfirmed actions are canceled, and the confirmed ones are reverted(see RevertibleAction).
+	CancelOnConfirmFailure bool
+	// MaxConfirmTries is the max tries to confirm a tcc if CancelOnConfirmFailure is true.
+	// No limit if it's zero.
+	MaxConfirmTries uint16
+	// WaitInterval is the poll interval of Wait, default is 1 second.
+	WaitInterval time.Duration
+	waiters      map[int64][]chan struct{}
+	waitMutex    sync.Mutex
+	listening    int32 // set to 1 by Listen.
 }
 
 type Action interface {
@@ -28,54 +52,200 @@ type Action interface {
 	Cancel() error
 }
 
+// LocalAction is an Action living in the same database as the mq. ConfirmTx and CancelTx are called
+// instead of Confirm and Cancel, with the transaction in which the action status is recorded,
+// so the business change and the action status commit atomically. If they return an error,
+// the whole transaction is rolled back.
+type LocalAction interface {
+	Action
+	ConfirmTx(tx *sql.Tx) error
+	CancelTx(tx *sql.Tx) error
+}
+
+// ContextAction is an Action which needs a context, the context carries the metadata of the tcc.
+// TryContext, ConfirmContext and CancelContext are called instead of Try, Confirm and Cancel.
+type ContextAction interface {
+	Action
+	TryContext(ctx context.Context) error
+	ConfirmContext(ctx context.Context) error
+	CancelContext(ctx context.Context) error
+}
+
 // name must be unique for the same mq.
+// The unique indexes of Key and Xid are created concurrently on the mq table if they don't exist,
+// which waits for the open transactions on the table, so the first start may take a while.
 func NewEngine(name string, mq *sqlmq.SqlMQ) *Engine {
 	stdTable := mq.Table.(*sqlmq.StdTable)
 	engine := &Engine{
 		sqlmq:       mq,
 		mqName:      "tcc-" + name,
 		mqTableName: stdTable.Name(),
-		actions:     make(map[string]Action),
+		actions:     make(map[string]registeredAction),
 	}
+	engine.Register(&ChildAction{})
 	if err := mq.Register(engine.mqName, engine.handle); err != nil {
 		panic(time.Now().Format(time.RFC3339Nano) + " " + err.Error())
 	}
+	addEngine(engine)
+	engine.createKeyIndex()
+	engine.createXidIndex()
 	return engine
 }
 
+type registeredAction struct {
+	prototype Action // the first tried action if registered by RegisterFunc.
+	decode    func(codec Codec, payload []byte) (Action, error)
+	version   int // the current version of the payload, see VersionedAction.
+}
+
+// Register actions by prototype values, the actions are decoded into new values of the same type.
 func (engine *Engine) Register(actions ...Action) {
 	engine.mutex.Lock()
 	defer engine.mutex.Unlock()
 	for _, action := range actions {
-		if name := action.Name(); engine.actions[name] != nil {
-			panic(time.Now().Format(time.RFC3339Nano) + " action " + name + " already registered")
-		} else {
-			engine.actions[name] = action
+		engine.register(action.Name(), registeredAction{
+			prototype: action, decode: decoderOf(action), version: versionOf(action),
+		})
+	}
+}
+
+// RegisterFunc registers an action by a decode function, which decodes the action from its
+// marshaled data(in the codec of the action), so the decoded action can be wired with
+// dependencies, such as DB handles, clients or config. The type of the first tried action of
+// the name is recorded, and actions of other types are refused to Try.
+func (engine *Engine) RegisterFunc(name string, decode func(raw []byte) (Action, error)) {
+	engine.RegisterFuncVersion(name, 0, decode)
+}
+
+// RegisterFuncVersion is the same as RegisterFunc, but with the current version of the payload,
+// which should be the same as the Version of the actions(see VersionedAction).
+func (engine *Engine) RegisterFuncVersion(
+	name string, version int, decode func(raw []byte) (Action, error),
+) {
+	engine.mutex.Lock()
+	defer engine.mutex.Unlock()
+	engine.register(name, registeredAction{
+		decode: func(_ Codec, payload []byte) (Action, error) {
+			return decode(payload)
+		},
+		version: version,
+	})
+}
+
+func (engine *Engine) register(name string, action registeredAction) {
+	if _, ok := engine.actions[name]; ok {
+		panic(time.Now().Format(time.RFC3339Nano) + " action " + name + " already registered")
+	}
+	engine.actions[name] = action
+}
+
+// decode actions into new values of the prototype's type.
+func decoderOf(prototype Action) func(codec Codec, payload []byte) (Action, error) {
+	typ := actionType(prototype)
+	_, saga := prototype.(sagaAction)
+	return func(codec Codec, payload []byte) (Action, error) {
+		actionPointer := reflect.New(typ)
+		if err := codec.Unmarshal(payload, actionPointer.Interface()); err != nil {
+			return nil, err
+		}
+		if saga {
+			return sagaAction{actionPointer.Elem().Interface().(SagaAction)}, nil
 		}
+		return actionPointer.Elem().Interface().(Action), nil
 	}
 }
 
 var errTccId = errors.New("tcc id error")
 
-func (engine *Engine) New(timeout time.Duration, concurrent bool) (*TCC, error) {
+// Option customizes the tcc created by New.
+type Option func(*tccData)
+
+// WithKey sets a business key which is unique per engine.
+// If a tcc of the key exists already, New returns it instead of creating a new one.
+func WithKey(key string) Option {
+	return func(data *tccData) {
+		data.Key = key
+	}
+}
+
+// WithMetadata attaches metadata(tenant id, order id, labels, etc.) to the tcc.
+// The metadata is passed to actions through their context, see MetadataFromContext.
+func WithMetadata(metadata map[string]string) Option {
+	return func(data *tccData) {
+		if data.Metadata == nil {
+			data.Metadata = make(map[string]string)
+		}
+		for k, v := range metadata {
+			data.Metadata[k] = v
+		}
+	}
+}
+
+func (engine *Engine) New(timeout time.Duration, concurrent bool, options ...Option) (*TCC, error) {
+	return engine.NewContext(context.Background(), timeout, concurrent, options...)
+}
+
+// NewContext is the same as New, but if ctx is done during the Try phase, the tcc stops trying
+// further actions and is canceled at once.
+func (engine *Engine) NewContext(
+	ctx context.Context, timeout time.Duration, concurrent bool, options ...Option,
+) (*TCC, error) {
 	now := time.Now()
 
+	data := &tccData{
+		Status:     statusTrying,
+		Concurrent: concurrent,
+	}
+	for _, option := range options {
+		option(data)
+	}
+	if data.Xid == "" && engine.XidGenerator != nil {
+		xid, err := engine.XidGenerator()
+		if err != nil {
+			return nil, err
+		}
+		data.Xid = xid
+	}
 	msg := &sqlmq.StdMessage{
-		Queue: engine.mqName,
-		Data: &tccData{
-			Status:     statusTrying,
-			Concurrent: concurrent,
-		},
+		Queue:     engine.mqName,
+		Data:      data,
 		CreatedAt: now,
 		RetryAt:   now.Add(timeout),
 	}
+	if engine.Lease > 0 {
+		// retry_at is the lease expiry, and the Try phase timeout is kept in Deadline.
+		deadline := msg.RetryAt
+		data.Deadline = &deadline
+		if leaseExpiry := now.Add(engine.Lease); leaseExpiry.Before(deadline) {
+			msg.RetryAt = leaseExpiry
+		}
+	}
 	if err := engine.sqlmq.Produce(nil, msg); err != nil {
+		if data.Key != "" && isUniqueViolation(err, engine.indexName("key")) {
+			return engine.getExisting(ctx, data.Key)
+		}
 		return nil, err
 	}
 	if msg.Id <= 0 {
 		return nil, errTccId
 	}
-	return &TCC{engine: engine, msg: msg}, nil
+	tcc := &TCC{engine: engine, msg: msg, ctx: ctx}
+	tcc.startWatching()
+	return tcc, nil
+}
+
+// the existing tcc of the key, which is used with ctx as a new one.
+func (engine *Engine) getExisting(ctx context.Context, key string) (*TCC, error) {
+	tcc, err := engine.getByKey(key)
+	if err != nil {
+		return nil, err
+	}
+	tcc.existed = true
+	tcc.ctx = ctx
+	if tcc.Status() == statusTrying {
+		tcc.startWatching()
+	}
+	return tcc, nil
 }
 
 func (engine *Engine) Run(timeout time.Duration, concurrent bool, actions ...Action) error {
@@ -83,30 +253,106 @@ func (engine *Engine) Run(timeout time.Duration, concurrent bool, actions ...Act
 	if err != nil {
 		return err
 	}
-	for _, action := range actions {
-		if err := tcc.Try(action); err != nil {
-			if err2 := tcc.Cancel(); err2 != nil {
-				engine.sqlmq.Logger.Error(err2)
+	_, err = tcc.run(actions)
+	return err
+}
+
+// RunContext is the same as Run, but uses NewContext to create the tcc.
+func (engine *Engine) RunContext(
+	ctx context.Context, timeout time.Duration, concurrent bool, actions ...Action,
+) error {
+	tcc, err := engine.NewContext(ctx, timeout, concurrent)
+	if err != nil {
+		return err
+	}
+	_, err = tcc.run(actions)
+	return err
+}
+
+// RunResult is the same as Run, but returns a Result to correlate the error with the tcc.
+func (engine *Engine) RunResult(timeout time.Duration, concurrent bool, actions ...Action) (
+	*Result, error,
+) {
+	tcc, err := engine.New(timeout, concurrent)
+	if err != nil {
+		return nil, err
+	}
+	return tcc.run(actions)
+}
+
+// ErrCanceled is sent to Future.Done if the tcc is canceled.
+var ErrCanceled = errors.New("tcc canceled")
+
+// Future is the handle of a tcc run by RunAsync.
+type Future struct {
+	Id int64
+	// Tried receives the result of the Try phase, nil means all actions are tried successfully.
+	Tried <-chan error
+	// Done receives the final result after all actions are confirmed or canceled:
+	// nil if the tcc is confirmed, ErrCanceled if it's canceled.
+	// It receives the error of Confirm at once if the confirm fails to be recorded.
+	Done <-chan error
+}
+
+// RunAsync returns once the tcc is created, the Try phase and the confirm or cancel
+// processing continue in background.
+func (engine *Engine) RunAsync(timeout time.Duration, concurrent bool, actions ...Action) (
+	*Future, error,
+) {
+	tcc, err := engine.New(timeout, concurrent)
+	if err != nil {
+		return nil, err
+	}
+	tried, done := make(chan error, 1), make(chan error, 1)
+	go func() {
+		_, err := tcc.tryAll(actions)
+		tried <- err
+		// a failure to record the confirm is not a failure of the Try phase.
+		if err == nil {
+			if err := tcc.Confirm(); err != nil {
+				done <- err
+				return
 			}
-			return err
 		}
-	}
-	return tcc.Confirm()
+		if status, err := tcc.Wait(context.Background()); err != nil {
+			done <- err
+		} else if status != statusConfirmed {
+			done <- ErrCanceled
+		} else {
+			done <- nil
+		}
+	}()
+	return &Future{Id: tcc.msg.Id, Tried: tried, Done: done}, nil
 }
 
 func (engine *Engine) checkAction(tried Action) error {
 	name := tried.Name()
 	engine.mutex.RLock()
-	registered := engine.actions[name]
+	registered, ok := engine.actions[name]
 	engine.mutex.RUnlock()
 
-	if registered == nil {
+	if !ok {
 		return fmt.Errorf("action %s is not registered", name)
 	}
-	if reflect.TypeOf(registered) != reflect.TypeOf(tried) {
+	if registered.prototype == nil {
+		engine.mutex.Lock()
+		if registered = engine.actions[name]; registered.prototype == nil {
+			registered.prototype = tried
+			engine.actions[name] = registered
+		}
+		engine.mutex.Unlock()
+	}
+	if registeredType, triedType := actionType(registered.prototype), actionType(tried); registeredType != triedType {
+		return fmt.Errorf(
+			`action %s has been registered with type "%v", but tried with type "%v"`,
+			name, registeredType, triedType,
+		)
+	}
+	// the recorded version must be the registered one, otherwise upgrades corrupt the payload.
+	if triedVersion := versionOf(tried); triedVersion != registered.version {
 		return fmt.Errorf(
-			`action %s has been registered with type "%T", but tried with type "%T"`,
-			name, registered, tried,
+			"action %s has been registered with version %d, but tried with version %d",
+			name, registered.version, triedVersion,
 		)
 	}
 	return nil
@@ -121,28 +367,44 @@ func (engine *Engine) handle(ctx context.Context, tx *sql.Tx, message sqlmq.Mess
 		return time.Hour, true, err
 	}
 	msg.Data = data
-	if retryAfter, canCommit, err := (&TCC{engine: engine, msg: msg}).confirmOrCancel(tx); err != nil {
+	if retryAfter, canCommit, err := (&TCC{engine: engine, msg: msg, ctx: ctx}).confirmOrCancel(tx); err != nil {
 		if retryAfter <= 0 {
 			retryAfter = sqlmq.GetRetryWait(msg.TriedCount)
 		}
 		return retryAfter, canCommit, err
 	}
+	engine.notifyCompleted(tx, msg.Id)
 	return 0, true, nil
 }
 
 var errActionNotRegistered = errors.New("action not registered")
 
-func (engine *Engine) unmarshalAction(name string, b []byte) (Action, error) {
+func (engine *Engine) unmarshalAction(ta tccAction) (Action, error) {
 	engine.mutex.RLock()
-	action := engine.actions[name]
+	action, ok := engine.actions[ta.Name]
+	var upgrades []func([]byte) ([]byte, error)
+	var err error
+	if ok {
+		upgrades, err = engine.upgradesOf(ta.Name, ta.Version, action.version)
+	}
 	engine.mutex.RUnlock()
 
-	if action == nil {
+	if !ok {
 		return nil, errActionNotRegistered
 	}
-	actionPointer := reflect.New(reflect.TypeOf(action))
-	if err := json.Unmarshal(b, actionPointer.Interface()); err != nil {
+	if err != nil {
+		return nil, err
+	}
+	codec, err := getCodec(ta.Codec)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := ta.payload()
+	if err != nil {
+		return nil, err
+	}
+	if payload, err = upgradePayload(upgrades, ta.Version, payload); err != nil {
 		return nil, err
 	}
-	return actionPointer.Elem().Interface().(Action), nil
+	return action.decode(codec, payload)
 }
@@ -0,0 +1,227 @@
+package tcc
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/lovego/errs"
+	"github.com/lovego/sqlmq"
+)
+
+// Get the tcc of id, id is the numeric id(int64) or the Xid(string) of the tcc.
+func (engine *Engine) Get(id interface{}) (*TCC, error) {
+	cond, err := engine.idCondition(id)
+	if err != nil {
+		return nil, err
+	}
+	return engine.get(cond, fmt.Sprintf("tcc(%v) not exists", id))
+}
+
+func (engine *Engine) getByKey(key string) (*TCC, error) {
+	// "data ? 'Key'" is the predicate of the partial index.
+	return engine.get("data ? 'Key' AND data->>'Key' = "+quote(key), fmt.Sprintf("tcc(key: %s) not exists", key))
+}
+
+func (engine *Engine) get(where, notExists string) (*TCC, error) {
+	querySql := fmt.Sprintf(`
+	SELECT id, data, status, created_at, tried_count, retry_at
+	FROM %s
+	WHERE queue = '%s' AND %s`,
+		engine.mqTableName, engine.mqName, where,
+	)
+	ctx, cancel := sqlTimeout()
+	defer cancel()
+	tcc, err := engine.scan(engine.sqlmq.DB.QueryRowContext(ctx, querySql))
+	if err == sql.ErrNoRows {
+		return nil, notExistsError(notExists)
+	}
+	return tcc, err
+}
+
+// the error of a tcc which doesn't exist.
+type notExistsError string
+
+func (err notExistsError) Error() string {
+	return string(err)
+}
+
+// Filter filters tccs for List and Stats.
+type Filter struct {
+	// status of tccs: "trying", "confirmed", "canceled", "reverted" or "confirm-failed".
+	Status   string
+	Metadata map[string]string // tccs having all of the metadata.
+	Limit    int               // max number of tccs returned by List, 0 means no limit.
+}
+
+// List tccs matching the filter, latest first.
+func (engine *Engine) List(filter Filter) ([]*TCC, error) {
+	where, err := filter.where()
+	if err != nil {
+		return nil, err
+	}
+	querySql := fmt.Sprintf(`
+	SELECT id, data, status, created_at, tried_count, retry_at
+	FROM %s
+	WHERE queue = '%s' %s
+	ORDER BY id DESC`,
+		engine.mqTableName, engine.mqName, where,
+	)
+	if filter.Limit > 0 {
+		querySql += fmt.Sprintf(" LIMIT %d", filter.Limit)
+	}
+	ctx, cancel := sqlTimeout()
+	defer cancel()
+	rows, err := engine.sqlmq.DB.QueryContext(ctx, querySql)
+	if err != nil {
+		return nil, errs.Trace(err)
+	}
+	defer rows.Close()
+	var tccs []*TCC
+	for rows.Next() {
+		tcc, err := engine.scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		tccs = append(tccs, tcc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errs.Trace(err)
+	}
+	return tccs, nil
+}
+
+// Stats counts tccs matching the filter by status.
+func (engine *Engine) Stats(filter Filter) (map[string]int, error) {
+	where, err := filter.where()
+	if err != nil {
+		return nil, err
+	}
+	querySql := fmt.Sprintf(`
+	SELECT data->'Status'#>>'{}', count(*)
+	FROM %s
+	WHERE queue = '%s' %s
+	GROUP BY 1`,
+		engine.mqTableName, engine.mqName, where,
+	)
+	ctx, cancel := sqlTimeout()
+	defer cancel()
+	rows, err := engine.sqlmq.DB.QueryContext(ctx, querySql)
+	if err != nil {
+		return nil, errs.Trace(err)
+	}
+	defer rows.Close()
+	var stats = make(map[string]int)
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, errs.Trace(err)
+		}
+		stats[status] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errs.Trace(err)
+	}
+	return stats, nil
+}
+
+func (filter Filter) where() (string, error) {
+	var conds []string
+	if filter.Status != "" {
+		conds = append(conds, "data->'Status' = to_jsonb("+quote(filter.Status)+"::text)")
+	}
+	if len(filter.Metadata) > 0 {
+		metadata, err := json.Marshal(filter.Metadata)
+		if err != nil {
+			return "", err
+		}
+		conds = append(conds, "data->'Metadata' @> "+quote(string(metadata))+"::jsonb")
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return "AND " + strings.Join(conds, " AND "), nil
+}
+
+func (engine *Engine) scan(row interface{ Scan(...interface{}) error }) (*TCC, error) {
+	var msg = &sqlmq.StdMessage{Queue: engine.mqName}
+	var data []byte
+	if err := row.Scan(
+		&msg.Id, &data, &msg.Status, &msg.CreatedAt, &msg.TriedCount, &msg.RetryAt,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, errs.Trace(err)
+	}
+	tccData := &tccData{}
+	if err := json.Unmarshal(data, tccData); err != nil {
+		return nil, err
+	}
+	msg.Data = tccData
+	return &TCC{engine: engine, msg: msg, ctx: context.Background()}, nil
+}
+
+func (engine *Engine) createKeyIndex() {
+	engine.createIndex("key", "Key")
+}
+
+// create the unique partial index of the data field. It's created concurrently without timeout,
+// because it waits for all the open transactions on the table(including the consumer's). An invalid
+// index left by a failed creation is dropped and created again. The index can also be created ahead
+// by a migration, see indexName for its name.
+func (engine *Engine) createIndex(name, field string) {
+	index := engine.indexName(name)
+	qualifiedIndex := index
+	if i := strings.IndexByte(engine.mqTableName, '.'); i > 0 {
+		qualifiedIndex = engine.mqTableName[:i+1] + index
+	}
+	ctx, db := context.Background(), engine.sqlmq.DB
+
+	var valid bool
+	err := db.QueryRowContext(ctx, fmt.Sprintf(
+		`SELECT indisvalid FROM pg_index WHERE indexrelid = to_regclass(%s)::oid`, quote(qualifiedIndex),
+	)).Scan(&valid)
+	switch {
+	case err == nil && valid:
+		return
+	case err == nil:
+		_, err = db.ExecContext(ctx, `DROP INDEX CONCURRENTLY IF EXISTS `+qualifiedIndex)
+	case err == sql.ErrNoRows:
+		err = nil
+	}
+	if err == nil {
+		_, err = db.ExecContext(ctx, fmt.Sprintf(
+			`CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS %s ON %s (queue, (data->>'%s'))
+			WHERE data ? '%s'`,
+			index, engine.mqTableName, field, field,
+		))
+	}
+	if err != nil {
+		panic(time.Now().Format(time.RFC3339Nano) + " " + err.Error())
+	}
+}
+
+func (engine *Engine) createXidIndex() {
+	engine.createIndex("xid", "Xid")
+}
+
+// the name of the index of the field, in the schema of the mq table: <table>_tcc_key or <table>_tcc_xid,
+// where the "." in a schema qualified table name is replaced by "_".
+func (engine *Engine) indexName(field string) string {
+	return strings.Replace(engine.mqTableName, ".", "_", 1) + "_tcc_" + field
+}
+
+// if err is a unique violation of the index.
+func isUniqueViolation(err error, index string) bool {
+	if e, ok := err.(*errs.Error); ok {
+		err = e.GetError()
+	}
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code == "23505" && pqErr.Constraint == index
+}
@@ -1,8 +1,10 @@
 package tcc
 
 import (
+	"context"
 	"fmt"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/lovego/sqlmq"
@@ -28,6 +30,29 @@ func ExampleEngine_Register() {
 	// action action1 already registered
 }
 
+func ExampleEngine_RegisterFunc() {
+	tcc, err := tccEngine.New(time.Minute, false)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(tcc.Try(testDepAction{Id: 1}))
+	fmt.Println(tcc.Try(&testDepAction{Id: 2}))
+	fmt.Println(tcc.Confirm())
+	fmt.Println(tcc.Wait(context.Background()))
+
+	defer func() {
+		fmt.Println(timePrefix.ReplaceAllString(recover().(string), ""))
+	}()
+	tccEngine.RegisterFunc("dep-action", nil)
+	// Output:
+	// <nil>
+	// action dep-action has been registered with type "tcc.testDepAction", but tried with type "*tcc.testDepAction"
+	// <nil>
+	// dep-action 1 Confirm by test-client
+	// confirmed <nil>
+	// action dep-action already registered
+}
+
 func ExampleEngine_New() {
 	tccEngine.mqName = "tcc-test2"
 	defer func() {
@@ -41,6 +66,108 @@ func ExampleEngine_New() {
 	// unknown queue: tcc-test2
 }
 
+func ExampleEngine_New_key() {
+	tcc1, err := tccEngine.New(time.Minute, false, WithKey("order-1"))
+	if err != nil {
+		panic(err)
+	}
+	tcc2, err := tccEngine.New(time.Minute, false, WithKey("order-1"))
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(tcc1.Existed(), tcc2.Existed(), tcc2.Id() == tcc1.Id(), tcc2.Status(), tcc2.Key())
+	fmt.Println(tcc1.Cancel())
+	// Output:
+	// false true true trying order-1
+	// <nil>
+}
+
+func ExampleEngine_NewContext() {
+	ctx, cancel := context.WithCancel(context.Background())
+	tcc, err := tccEngine.NewContext(ctx, time.Minute, false)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(tcc.Try(testAction1{}))
+	cancel()
+	fmt.Println(tcc.Try(testAction2{}))
+	fmt.Println(tcc.Wait(context.Background()))
+	// Output:
+	// action1 Try
+	// <nil>
+	// context canceled
+	// action1 Cancel
+	// canceled <nil>
+}
+
+func ExampleEngine_NewContext_key() {
+	tcc1, err := tccEngine.New(time.Minute, false, WithKey("order-ctx"))
+	if err != nil {
+		panic(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	tcc2, err := tccEngine.NewContext(ctx, time.Minute, false, WithKey("order-ctx"))
+	if err != nil {
+		panic(err)
+	}
+	cancel()
+	fmt.Println(tcc2.Existed(), tcc2.Try(testAction1{}))
+	fmt.Println(tcc1.Wait(context.Background()))
+	// Output:
+	// true context canceled
+	// canceled <nil>
+}
+
+func ExampleEngine_RunContext() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	fmt.Println(tccEngine.RunContext(ctx, time.Minute, false, testAction1{}))
+	// Output:
+	// context canceled
+}
+
+func ExampleEngine_Get() {
+	tcc, err := tccEngine.New(time.Minute, false)
+	if err != nil {
+		panic(err)
+	}
+	got, err := tccEngine.Get(tcc.Id())
+	fmt.Println(got.Id() == tcc.Id(), got.Status(), got.Existed(), err)
+	fmt.Println(tcc.Cancel())
+	_, err = tccEngine.Get(-1)
+	fmt.Println(err)
+	// Output:
+	// true trying false <nil>
+	// <nil>
+	// tcc(-1) not exists
+}
+
+func ExampleEngine_List() {
+	tcc, err := tccEngine.New(time.Minute, false, WithMetadata(map[string]string{
+		"tenant": "t1", "order": "o1",
+	}))
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(tcc.Try(testContextAction{}))
+	fmt.Println(tcc.Confirm())
+	fmt.Println(tcc.Wait(context.Background()))
+
+	tccs, err := tccEngine.List(Filter{Metadata: map[string]string{"tenant": "t1"}})
+	fmt.Println(len(tccs), tccs[0].Id() == tcc.Id(), tccs[0].Metadata()["order"], err)
+	fmt.Println(tccEngine.Stats(Filter{Metadata: map[string]string{"tenant": "t1"}}))
+	fmt.Println(tccEngine.Stats(Filter{Metadata: map[string]string{"tenant": "t2"}}))
+	// Output:
+	// context-action Try t1
+	// <nil>
+	// <nil>
+	// context-action Confirm t1
+	// confirmed <nil>
+	// 1 true o1 <nil>
+	// map[confirmed:1] <nil>
+	// map[] <nil>
+}
+
 func ExampleEngine_handle() {
 	fmt.Println(tccEngine.handle(nil, nil, &sqlmq.StdMessage{
 		Data: []byte{},
@@ -65,6 +192,124 @@ func ExampleEngine_handle() {
 	// 1h0m0s true action1: json: cannot unmarshal number into Go value of type tcc.testAction1
 }
 
+func ExampleEngine_Wait() {
+	tcc, err := tccEngine.New(time.Minute, false)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(tcc.Try(testAction2{}))
+	fmt.Println(tcc.Confirm())
+	fmt.Println(tcc.Wait(context.Background()))
+
+	_, err = tccEngine.Wait(context.Background(), -1)
+	fmt.Println(err)
+
+	tcc, err = tccEngine.New(time.Minute, false)
+	if err != nil {
+		panic(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = tcc.Wait(ctx)
+	fmt.Println(err)
+	fmt.Println(tcc.Cancel())
+	// Output:
+	// action2 Try
+	// <nil>
+	// <nil>
+	// action2 Confirm
+	// confirmed <nil>
+	// tcc(-1) not exists
+	// context deadline exceeded
+	// <nil>
+}
+
+func ExampleEngine_Listen() {
+	engine := NewEngine(strings.Repeat("long-engine-name-", 4), testMQ)
+	engine.Register(testAction2{})
+	fmt.Println(engine.Listen(testDSN))
+	fmt.Println(len(engine.notifyChannel()) < 64)
+
+	tcc, err := engine.New(time.Minute, false)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(tcc.Try(testAction2{}))
+	fmt.Println(tcc.Confirm())
+	fmt.Println(tcc.Wait(context.Background()))
+	// Output:
+	// <nil>
+	// true
+	// action2 Try
+	// <nil>
+	// <nil>
+	// action2 Confirm
+	// confirmed <nil>
+}
+
+func ExampleEngine_RunAsync() {
+	future, err := tccEngine.RunAsync(time.Minute, false, testAction1{}, testAction2{})
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(future.Id > 0, <-future.Tried)
+	fmt.Println(<-future.Done)
+
+	future, err = tccEngine.RunAsync(time.Minute, false, testAction1{}, testAction3{})
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(<-future.Tried)
+	fmt.Println(<-future.Done)
+	// Output:
+	// action1 Try
+	// action2 Try
+	// true <nil>
+	// action1 Confirm
+	// action2 Confirm
+	// <nil>
+	// action1 Try
+	// action3 Try
+	// error happened
+	// action3 Cancel
+	// action1 Cancel
+	// tcc canceled
+}
+
+func ExampleEngine_RunResult() {
+	result, err := tccEngine.RunResult(time.Minute, false, testAction1{}, testAction3{}, testAction2{})
+	fmt.Println(err)
+	fmt.Println(result.Id > 0, result.Tried, result.Failed, result.Canceled, result.CancelError)
+	time.Sleep(time.Second)
+	// Output:
+	// action1 Try
+	// action3 Try
+	// error happened
+	// true [{<nil>}] {} true <nil>
+	// action3 Cancel
+	// action1 Cancel
+}
+
+// testDepAction needs a client injected by the decode function of RegisterFunc.
+type testDepAction struct {
+	Id     int
+	client string
+}
+
+func (ta testDepAction) Name() string {
+	return "dep-action"
+}
+func (ta testDepAction) Try() error {
+	return nil
+}
+func (ta testDepAction) Confirm() error {
+	fmt.Printf("dep-action %d Confirm by %s\n", ta.Id, ta.client)
+	return nil
+}
+func (ta testDepAction) Cancel() error {
+	return nil
+}
+
 type testAction struct {
 }
 
@@ -0,0 +1,176 @@
+package tcc
+
+import (
+	"context"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/lovego/errs"
+)
+
+// status of a sqlmq message whose handling has finished.
+const (
+	mqStatusDone    = "done"
+	mqStatusGivenUp = "givenUp"
+)
+
+// Listen listens for the completion notifications sent by the mq handler, so Wait returns at once
+// when a tcc completes, instead of at the next poll. dsn is the connection string of the mq's database.
+// The notifications are sent only by the engines which have called Listen.
+func (engine *Engine) Listen(dsn string) error {
+	listener := pq.NewListener(dsn, time.Second, time.Minute, nil)
+	if err := listener.Listen(engine.notifyChannel()); err != nil {
+		listener.Close()
+		return err
+	}
+	atomic.StoreInt32(&engine.listening, 1)
+	go engine.dispatchNotifications(listener)
+	return nil
+}
+
+// Wait blocks until every action of the tcc has been confirmed or canceled, or ctx is done.
+// id is the numeric id(int64) or the Xid(string) of the tcc.
+// It returns the final status of the tcc: "confirmed", "canceled", "reverted" or "confirm-failed".
+func (engine *Engine) Wait(ctx context.Context, tccId interface{}) (string, error) {
+	id, err := engine.resolveId(tccId)
+	if err != nil {
+		return "", err
+	}
+	notified := engine.addWaiter(id)
+	defer engine.removeWaiter(id, notified)
+
+	interval := engine.WaitInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	for {
+		if status, done, err := engine.finalStatus(id); err != nil || done {
+			return status, err
+		}
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return "", ctx.Err()
+		case <-notified:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+func (tcc *TCC) Wait(ctx context.Context) (string, error) {
+	return tcc.engine.Wait(ctx, tcc.msg.Id)
+}
+
+func (engine *Engine) finalStatus(id int64) (string, bool, error) {
+	querySql := fmt.Sprintf(`
+	SELECT status, data->'Status'#>>'{}' as tcc_status
+	FROM %s
+	WHERE id = %d AND queue = '%s'`,
+		engine.mqTableName, id, engine.mqName,
+	)
+	var status, tccStatus string
+	ctx, cancel := sqlTimeout()
+	defer cancel()
+	if err := engine.sqlmq.DB.QueryRowContext(ctx, querySql).Scan(&status, &tccStatus); err != nil {
+		if err == sql.ErrNoRows {
+			return "", false, fmt.Errorf("tcc(%d) not exists", id)
+		}
+		return "", false, errs.Trace(err)
+	}
+	switch status {
+	case mqStatusDone:
+		return tccStatus, true, nil
+	case mqStatusGivenUp:
+		return tccStatus, false, fmt.Errorf("tcc(%d) is given up", id)
+	}
+	return "", false, nil
+}
+
+// notify the waiters that the tcc is completed, the notification is sent only if tx commits.
+// It's best-effort(waiters poll anyway), so a failure is logged and rolled back to a savepoint,
+// instead of rolling back the finished confirm or cancel.
+func (engine *Engine) notifyCompleted(tx *sql.Tx, id int64) {
+	if atomic.LoadInt32(&engine.listening) == 0 {
+		return
+	}
+	ctx, cancel := sqlTimeout()
+	defer cancel()
+	if _, err := tx.ExecContext(ctx, `SAVEPOINT tcc_notify`); err != nil {
+		engine.sqlmq.Logger.Error(errs.Trace(err))
+		return
+	}
+	notifySql := fmt.Sprintf(`SELECT pg_notify(%s, '%d')`, quote(engine.notifyChannel()), id)
+	if _, err := tx.ExecContext(ctx, notifySql); err != nil {
+		engine.sqlmq.Logger.Error(errs.Trace(err))
+		if _, err := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT tcc_notify`); err != nil {
+			engine.sqlmq.Logger.Error(errs.Trace(err))
+		}
+	}
+}
+
+// a hash of the table and queue, so it always fits in the 63 bytes limit of channel names.
+func (engine *Engine) notifyChannel() string {
+	sum := sha1.Sum([]byte(engine.mqTableName + "." + engine.mqName))
+	return "tcc_" + hex.EncodeToString(sum[:])
+}
+
+func (engine *Engine) dispatchNotifications(listener *pq.Listener) {
+	for notification := range listener.Notify {
+		engine.waitMutex.Lock()
+		if notification == nil {
+			// reconnected, notifications may have been lost, so wake up all waiters to poll.
+			for id := range engine.waiters {
+				engine.wakeWaiters(id)
+			}
+		} else if id, err := strconv.ParseInt(notification.Extra, 10, 64); err == nil {
+			engine.wakeWaiters(id)
+		}
+		engine.waitMutex.Unlock()
+	}
+}
+
+func (engine *Engine) addWaiter(id int64) chan struct{} {
+	notified := make(chan struct{}, 1)
+	engine.waitMutex.Lock()
+	defer engine.waitMutex.Unlock()
+	if engine.waiters == nil {
+		engine.waiters = make(map[int64][]chan struct{})
+	}
+	engine.waiters[id] = append(engine.waiters[id], notified)
+	return notified
+}
+
+func (engine *Engine) removeWaiter(id int64, notified chan struct{}) {
+	engine.waitMutex.Lock()
+	defer engine.waitMutex.Unlock()
+	waiters := engine.waiters[id]
+	for i := range waiters {
+		if waiters[i] == notified {
+			waiters = append(waiters[:i], waiters[i+1:]...)
+			break
+		}
+	}
+	if len(waiters) > 0 {
+		engine.waiters[id] = waiters
+	} else {
+		delete(engine.waiters, id)
+	}
+}
+
+// must be called with waitMutex locked.
+func (engine *Engine) wakeWaiters(id int64) {
+	for _, notified := range engine.waiters[id] {
+		select {
+		case notified <- struct{}{}:
+		default:
+		}
+	}
+}
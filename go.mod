@@ -1,6 +1,6 @@
 module github.com/lovego/tcc
 
-go 1.14
+go 1.18
 
 require (
 	github.com/lib/pq v1.10.2
@@ -8,3 +8,8 @@ require (
 	github.com/lovego/logger v0.0.1
 	github.com/lovego/sqlmq v0.0.9
 )
+
+require (
+	github.com/lovego/sleep v0.0.2 // indirect
+	github.com/lovego/tracer v0.0.1 // indirect
+)
@@ -0,0 +1,168 @@
+package tcc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const (
+	groupQuorum = "quorum"
+	groupRace   = "race"
+)
+
+// tccGroup is a group of actions, of which only the chosen ones are confirmed,
+// the others are canceled even if the tcc is confirmed.
+type tccGroup struct {
+	Mode    string // "quorum" or "race"
+	K       int    `json:",omitempty"` // least number of succeeded actions of a quorum.
+	Actions []int  // indexes of the actions in tccData.Actions.
+	Chosen  []int  `json:",omitempty"` // indexes of the chosen actions.
+}
+
+// TryQuorum tries the actions concurrently, it succeeds if at least k actions succeed.
+// The first k succeeded actions in order are chosen and returned, the surplus succeeded actions
+// and the failed actions are canceled, only the chosen ones are confirmed.
+func (tcc *TCC) TryQuorum(k int, actions ...Action) ([]Action, error) {
+	if k <= 0 || k > len(actions) {
+		return nil, fmt.Errorf("invalid quorum %d of %d actions", k, len(actions))
+	}
+	group, err := tcc.recordGroup(actions)
+	if err != nil {
+		return nil, err
+	}
+	group.Mode, group.K = groupQuorum, k
+
+	var errs = make([]error, len(actions))
+	var wg sync.WaitGroup
+	for i, action := range actions {
+		wg.Add(1)
+		go func(i int, action Action) {
+			defer wg.Done()
+			errs[i] = tcc.invokeTry(action)
+		}(i, action)
+	}
+	wg.Wait()
+
+	var chosen []Action
+	var errMsgs []string
+	for i, err := range errs {
+		if err != nil {
+			errMsgs = append(errMsgs, actions[i].Name()+": "+err.Error())
+		} else if len(chosen) < k {
+			chosen = append(chosen, actions[i])
+			group.Chosen = append(group.Chosen, group.Actions[i])
+		}
+	}
+	if len(chosen) < k {
+		chosen, group.Chosen = nil, nil
+	}
+	if err := tcc.addGroup(group); err != nil {
+		return nil, err
+	}
+	if chosen == nil {
+		return nil, fmt.Errorf("quorum %d of %d not reached: %s", k, len(actions), strings.Join(errMsgs, "; "))
+	}
+	return chosen, nil
+}
+
+// TryRace tries the actions concurrently, the first succeeded action wins and is returned at once.
+// The other actions are canceled even if the tcc is confirmed, and Confirm or Cancel of the tcc
+// waits for their Trys to finish.
+func (tcc *TCC) TryRace(actions ...Action) (Action, error) {
+	if len(actions) == 0 {
+		return nil, errors.New("no actions to race")
+	}
+	group, err := tcc.recordGroup(actions)
+	if err != nil {
+		return nil, err
+	}
+	group.Mode = groupRace
+
+	type result struct {
+		i   int
+		err error
+	}
+	var results = make(chan result, len(actions))
+	tcc.pending.Add(len(actions))
+	for i, action := range actions {
+		go func(i int, action Action) {
+			defer tcc.pending.Done()
+			results <- result{i, tcc.invokeTry(action)}
+		}(i, action)
+	}
+
+	var errMsgs []string
+	for range actions {
+		if r := <-results; r.err != nil {
+			errMsgs = append(errMsgs, actions[r.i].Name()+": "+r.err.Error())
+		} else {
+			group.Chosen = []int{group.Actions[r.i]}
+			if err := tcc.addGroup(group); err != nil {
+				return nil, err
+			}
+			return actions[r.i], nil
+		}
+	}
+	if err := tcc.addGroup(group); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("all of %d actions failed: %s", len(actions), strings.Join(errMsgs, "; "))
+}
+
+// check and record the actions of a group.
+func (tcc *TCC) recordGroup(actions []Action) (*tccGroup, error) {
+	for _, action := range actions {
+		if err := tcc.engine.checkAction(action); err != nil {
+			return nil, err
+		}
+	}
+	if err := tcc.ctx.Err(); err != nil {
+		return nil, err
+	}
+	var group = &tccGroup{}
+	for _, action := range actions {
+		marshaledAction, err := marshalAction(action)
+		if err != nil {
+			return nil, err
+		}
+		index, err := tcc.appendAction(marshaledAction)
+		if err != nil {
+			return nil, err
+		}
+		group.Actions = append(group.Actions, index)
+	}
+	return group, nil
+}
+
+func (tcc *TCC) addGroup(group *tccGroup) error {
+	marshaledGroup, err := json.Marshal(group)
+	if err != nil {
+		return err
+	}
+	_, err = tcc.update(`data = jsonb_set(data, '{Groups}'::text[],
+		coalesce(data->'Groups', '[]'::jsonb) || `+quote(string(marshaledGroup))+`::jsonb
+	)`, statusTrying, "add group", nil)
+	return err
+}
+
+// if the action is excluded by a group it belongs to.
+func (data *tccData) excluded(actionIndex int) bool {
+	for _, group := range data.Groups {
+		if containsInt(group.Actions, actionIndex) && !containsInt(group.Chosen, actionIndex) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInt(slice []int, i int) bool {
+	for _, v := range slice {
+		if v == i {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package tcc
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+func ExampleTCC_TryQuorum() {
+	tcc, err := tccEngine.New(time.Minute, false)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(tcc.TryQuorum(2,
+		testGroupAction{N: 1, Fail: true}, testGroupAction{N: 2}, testGroupAction{N: 3}, testGroupAction{N: 4},
+	))
+	fmt.Println(tcc.Confirm())
+	fmt.Println(tcc.Wait(context.Background()))
+
+	tcc, err = tccEngine.New(time.Minute, false)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(tcc.TryQuorum(2, testGroupAction{N: 1, Fail: true}, testGroupAction{N: 2}))
+	fmt.Println(tcc.TryQuorum(0))
+	fmt.Println(tcc.Cancel())
+	fmt.Println(tcc.Wait(context.Background()))
+	// Output:
+	// [{2 false 0s} {3 false 0s}] <nil>
+	// <nil>
+	// group 1 Cancel
+	// group 2 Confirm
+	// group 3 Confirm
+	// group 4 Cancel
+	// confirmed <nil>
+	// [] quorum 2 of 2 not reached: group-action: group 1 failed
+	// [] invalid quorum 0 of 0 actions
+	// <nil>
+	// group 2 Cancel
+	// group 1 Cancel
+	// canceled <nil>
+}
+
+func ExampleTCC_TryRace() {
+	tcc, err := tccEngine.New(time.Minute, false)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(tcc.TryRace(
+		testGroupAction{N: 1, Delay: 100 * time.Millisecond},
+		testGroupAction{N: 2, Fail: true},
+		testGroupAction{N: 3, Delay: 10 * time.Millisecond},
+	))
+	fmt.Println(tcc.Confirm())
+	fmt.Println(tcc.Wait(context.Background()))
+
+	tcc, err = tccEngine.New(time.Minute, false)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(tcc.TryRace(testGroupAction{N: 1, Fail: true}))
+	fmt.Println(tcc.TryRace())
+	fmt.Println(tcc.Cancel())
+	fmt.Println(tcc.Wait(context.Background()))
+	// Output:
+	// {3 false 10ms} <nil>
+	// <nil>
+	// group 1 Cancel
+	// group 2 Cancel
+	// group 3 Confirm
+	// confirmed <nil>
+	// <nil> all of 1 actions failed: group-action: group 1 failed
+	// <nil> no actions to race
+	// <nil>
+	// group 1 Cancel
+	// canceled <nil>
+}
+
+// testGroupAction doesn't print in Try, because it's tried concurrently.
+type testGroupAction struct {
+	N     int
+	Fail  bool
+	Delay time.Duration
+}
+
+func (ta testGroupAction) Name() string {
+	return "group-action"
+}
+func (ta testGroupAction) Try() error {
+	time.Sleep(ta.Delay)
+	if ta.Fail {
+		return fmt.Errorf("group %d failed", ta.N)
+	}
+	return nil
+}
+func (ta testGroupAction) Confirm() error {
+	fmt.Printf("group %d Confirm\n", ta.N)
+	return nil
+}
+func (ta testGroupAction) Cancel() error {
+	fmt.Printf("group %d Cancel\n", ta.N)
+	return nil
+}
@@ -0,0 +1,77 @@
+package tcc
+
+import (
+	"time"
+)
+
+// RetryPolicy is the policy to retry the Try of an action within the Try phase timeout,
+// before giving up and canceling the tcc.
+type RetryPolicy struct {
+	Attempts int // max attempts, including the first one.
+	// Backoff returns the wait before the next attempt, attempt starts from 1. No wait if it's nil.
+	Backoff func(attempt int) time.Duration
+	// Retryable reports if the error should be retried. All errors are retried if it's nil.
+	Retryable func(err error) bool
+}
+
+// RetryableAction is an Action whose Try is retried by its policy.
+type RetryableAction interface {
+	Action
+	TryRetryPolicy() RetryPolicy
+}
+
+// ExponentialBackoff returns a Backoff which doubles from base, but not more than max.
+func ExponentialBackoff(base, max time.Duration) func(attempt int) time.Duration {
+	return func(attempt int) time.Duration {
+		wait := base
+		for i := 1; i < attempt && wait < max; i++ {
+			wait *= 2
+		}
+		if wait > max {
+			wait = max
+		}
+		return wait
+	}
+}
+
+// invoke the Try of the action, retry it if it's a RetryableAction.
+func (tcc *TCC) invokeTry(action Action) error {
+	tcc.pending.Add(1)
+	defer tcc.pending.Done()
+	ctx := WithTCC(tcc.context(), tcc)
+	err := tryAction(ctx, action)
+	retryable, ok := action.(RetryableAction)
+	if !ok {
+		return err
+	}
+	policy := retryable.TryRetryPolicy()
+	for attempt := 1; err != nil && attempt < policy.Attempts; attempt++ {
+		if policy.Retryable != nil && !policy.Retryable(err) {
+			break
+		}
+		var wait time.Duration
+		if policy.Backoff != nil {
+			wait = policy.Backoff(attempt)
+		}
+		if time.Now().Add(wait).After(tcc.deadline()) {
+			break
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		err = tryAction(ctx, action)
+	}
+	return err
+}
+
+// the Try phase timeout.
+func (tcc *TCC) deadline() time.Time {
+	if deadline := tcc.msg.Data.(*tccData).Deadline; deadline != nil {
+		return *deadline
+	}
+	return tcc.msg.RetryAt
+}
@@ -0,0 +1,84 @@
+package tcc
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+func ExampleRetryPolicy() {
+	fmt.Println(tccEngine.Run(time.Minute, false, &testRetryAction{Failures: 2}))
+	fmt.Println(tccEngine.Run(time.Minute, false, &testRetryAction{Failures: 5}))
+	fmt.Println(tccEngine.Run(time.Minute, false, &testRetryAction{Failures: 5, Permanent: true}))
+	fmt.Println(tccEngine.Run(100*time.Millisecond, false, &testRetryAction{Failures: 5, Wait: time.Second}))
+	// Output:
+	// retry Try 1
+	// retry Try 2
+	// retry Try 3
+	// <nil>
+	// retry Try 1
+	// retry Try 2
+	// retry Try 3
+	// failure 3
+	// retry Try 1
+	// permanent failure 1: permanent
+	// retry Try 1
+	// failure 1
+}
+
+func ExampleExponentialBackoff() {
+	backoff := ExponentialBackoff(10*time.Millisecond, 50*time.Millisecond)
+	for attempt := 1; attempt <= 5; attempt++ {
+		fmt.Println(backoff(attempt))
+	}
+	// Output:
+	// 10ms
+	// 20ms
+	// 40ms
+	// 50ms
+	// 50ms
+}
+
+var errPermanent = errors.New("permanent")
+
+type testRetryAction struct {
+	Failures  int
+	Permanent bool
+	Wait      time.Duration
+	tried     int
+}
+
+func (ta *testRetryAction) Name() string {
+	return "retry-action"
+}
+func (ta *testRetryAction) Try() error {
+	ta.tried++
+	fmt.Println("retry Try", ta.tried)
+	if ta.tried <= ta.Failures {
+		if ta.Permanent {
+			return fmt.Errorf("permanent failure %d: %w", ta.tried, errPermanent)
+		}
+		return fmt.Errorf("failure %d", ta.tried)
+	}
+	return nil
+}
+func (ta *testRetryAction) Confirm() error {
+	return nil
+}
+func (ta *testRetryAction) Cancel() error {
+	return nil
+}
+func (ta *testRetryAction) TryRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		Attempts: 3,
+		Backoff: func(int) time.Duration {
+			if ta.Wait > 0 {
+				return ta.Wait
+			}
+			return 10 * time.Millisecond
+		},
+		Retryable: func(err error) bool {
+			return !errors.Is(err, errPermanent)
+		},
+	}
+}
@@ -0,0 +1,140 @@
+package tcc
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lovego/errs"
+	"github.com/lovego/sqlmq"
+)
+
+const statusReverted = "reverted"
+
+// ErrCleaned is wrapped in the error returned by Revert if the tcc doesn't exist,
+// which may have been cleaned by the mq.
+var ErrCleaned = errors.New("it may have been cleaned by the mq")
+
+// RevertibleAction is an Action which can be reverted after the tcc is confirmed, such as a refund.
+type RevertibleAction interface {
+	Action
+	Revert() error
+}
+
+// Revert a confirmed tcc, id is the numeric id(int64) or the Xid(string) of the tcc.
+// It creates a new tcc linked to the original one, whose actions are the confirmed actions
+// of the original tcc, and the mq reverts them in reverse order. All the confirmed actions must be
+// RevertibleActions. The new tcc is returned, use its Wait method to wait for the revert to complete.
+// The original tcc must still be in the mq table, so the keep time of the done messages in the
+// mq table(see sqlmq.NewStdTable) must cover the revert window, otherwise ErrCleaned is returned.
+func (engine *Engine) Revert(id interface{}) (*TCC, error) {
+	original, err := engine.Get(id)
+	if _, ok := err.(notExistsError); ok {
+		return nil, fmt.Errorf("%v, %w", err, ErrCleaned)
+	} else if err != nil {
+		return nil, err
+	}
+	data := original.msg.Data.(*tccData)
+	if data.Status != statusConfirmed {
+		return nil, fmt.Errorf("tcc(%d) is %s, cann't Revert", original.msg.Id, data.Status)
+	}
+	if data.RevertedBy > 0 {
+		return nil, fmt.Errorf("tcc(%d) is reverted by tcc(%d) already", original.msg.Id, data.RevertedBy)
+	}
+	if original.msg.Status != mqStatusDone {
+		return nil, fmt.Errorf("tcc(%d) is still confirming, cann't Revert", original.msg.Id)
+	}
+
+	var actions []tccAction
+	for _, ta := range data.Actions {
+		if ta.Status != statusConfirmed {
+			continue
+		}
+		if action, err := engine.unmarshalAction(ta); err != nil {
+			return nil, errors.New(ta.Name + ": " + err.Error())
+		} else if _, ok := action.(RevertibleAction); !ok {
+			return nil, fmt.Errorf("action %s is not revertible", ta.Name)
+		}
+		actions = append(actions, tccAction{Name: ta.Name, Raw: ta.Raw, Codec: ta.Codec, Version: ta.Version})
+	}
+
+	now := time.Now()
+	msg := &sqlmq.StdMessage{
+		Queue: engine.mqName,
+		Data: &tccData{
+			Status:   statusReverted,
+			Metadata: data.Metadata,
+			RevertOf: original.msg.Id,
+			Actions:  actions,
+		},
+		CreatedAt: now,
+		RetryAt:   now,
+	}
+	if err := engine.produceRevert(original.msg.Id, msg); err != nil {
+		return nil, err
+	}
+	return &TCC{engine: engine, msg: msg, ctx: original.ctx}, nil
+}
+
+// produce the revert tcc and link it to the original tcc in a transaction.
+func (engine *Engine) produceRevert(originalId int64, msg *sqlmq.StdMessage) error {
+	tx, err := engine.sqlmq.DB.Begin()
+	if err != nil {
+		return errs.Trace(err)
+	}
+	defer tx.Rollback()
+
+	if err := engine.sqlmq.Produce(tx, msg); err != nil {
+		return err
+	}
+	updateSql := fmt.Sprintf(`
+	UPDATE %s
+	SET data = jsonb_set(data, '{RevertedBy}'::text[], to_jsonb(%d))
+	WHERE id = %d AND queue = '%s' AND data->'Status' = to_jsonb('%s'::text) AND NOT data ? 'RevertedBy'`,
+		engine.mqTableName,
+		msg.Id,
+		originalId, engine.mqName, statusConfirmed,
+	)
+	ctx, cancel := sqlTimeout()
+	defer cancel()
+	if result, err := tx.ExecContext(ctx, updateSql); err != nil {
+		return errs.Trace(err)
+	} else if n, err := result.RowsAffected(); err != nil {
+		return errs.Trace(err)
+	} else if n != 1 {
+		return fmt.Errorf("tcc(%d) is reverted already", originalId)
+	}
+	if err := tx.Commit(); err != nil {
+		return errs.Trace(err)
+	}
+	engine.sqlmq.NotifyConsumeAt(time.Now(), "tcc.Revert")
+	return nil
+}
+
+func (tcc *TCC) revertSerially(data *tccData, tx *sql.Tx) (time.Duration, bool, error) {
+	for i := len(data.Actions) - 1; i >= 0; i-- {
+		action := data.Actions[i]
+		if action.Status != statusReverted {
+			if retryAfter, canCommit, err := action.revert(tcc, tx, i); err != nil {
+				return retryAfter, canCommit, errors.New(action.Name + ": " + err.Error())
+			}
+		}
+	}
+	return 0, true, nil
+}
+
+func (ta tccAction) revert(tcc *TCC, tx *sql.Tx, actionIndex int) (time.Duration, bool, error) {
+	action, err := tcc.engine.unmarshalAction(ta)
+	if err != nil {
+		return time.Hour, true, err
+	}
+	revertible, ok := action.(RevertibleAction)
+	if !ok {
+		return time.Hour, true, fmt.Errorf("action %s is not revertible", ta.Name)
+	}
+	if err := revertible.Revert(); err != nil {
+		return 0, true, err
+	}
+	return setActionStatus(tcc, tx, actionIndex, statusReverted, "revert action")
+}
@@ -0,0 +1,91 @@
+package tcc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+var tccIds = regexp.MustCompile(`tcc\(\d+\)`)
+
+func ExampleEngine_Revert() {
+	tcc, err := tccEngine.New(time.Minute, false)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(tcc.Try(testRevertAction{N: 1}), tcc.Try(testRevertAction{N: 2}))
+	_, err = tccEngine.Revert(tcc.Id())
+	fmt.Println(tccIds.ReplaceAllString(err.Error(), "tcc(1)"))
+	fmt.Println(tcc.Confirm())
+	fmt.Println(tcc.Wait(context.Background()))
+
+	revert, err := tccEngine.Revert(tcc.Id())
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(revert.Wait(context.Background()))
+	_, err = tccEngine.Revert(tcc.Id())
+	fmt.Println(tccIds.ReplaceAllString(err.Error(), "tcc(1)"))
+
+	got, err := tccEngine.Get(tcc.Id())
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(
+		got.msg.Data.(*tccData).RevertedBy == revert.Id(), revert.msg.Data.(*tccData).RevertOf == tcc.Id(),
+	)
+
+	_, err = tccEngine.Revert(int64(-1))
+	fmt.Println(err, errors.Is(err, ErrCleaned))
+	// Output:
+	// <nil> <nil>
+	// tcc(1) is trying, cann't Revert
+	// <nil>
+	// confirmed <nil>
+	// revert 2 Revert
+	// revert 1 Revert
+	// reverted <nil>
+	// tcc(1) is reverted by tcc(1) already
+	// true true
+	// tcc(-1) not exists, it may have been cleaned by the mq true
+}
+
+func ExampleEngine_Revert_notRevertible() {
+	tcc, err := tccEngine.New(time.Minute, false)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(tcc.Try(testAction2{}), tcc.Confirm())
+	fmt.Println(tcc.Wait(context.Background()))
+	_, err = tccEngine.Revert(tcc.Id())
+	fmt.Println(err)
+	// Output:
+	// action2 Try
+	// <nil> <nil>
+	// action2 Confirm
+	// confirmed <nil>
+	// action action2 is not revertible
+}
+
+type testRevertAction struct {
+	N int
+}
+
+func (ta testRevertAction) Name() string {
+	return "revert-action"
+}
+func (ta testRevertAction) Try() error {
+	return nil
+}
+func (ta testRevertAction) Confirm() error {
+	return nil
+}
+func (ta testRevertAction) Cancel() error {
+	return nil
+}
+func (ta testRevertAction) Revert() error {
+	fmt.Printf("revert %d Revert\n", ta.N)
+	return nil
+}
@@ -0,0 +1,103 @@
+package tcc
+
+import (
+	"database/sql"
+	"encoding/json"
+	"reflect"
+	"time"
+)
+
+// SagaAction is an action of a saga, which has no reservation step, but only do and undo.
+type SagaAction interface {
+	Name() string
+	Execute() error
+	// Compensate undoes Execute. It's called also if Execute failed or didn't complete,
+	// so it should handle the case that Execute has not taken effect.
+	Compensate() error
+}
+
+// RegisterSaga registers the actions used by Saga.
+func (engine *Engine) RegisterSaga(actions ...SagaAction) {
+	var sagaActions = make([]Action, len(actions))
+	for i, action := range actions {
+		sagaActions[i] = sagaAction{action}
+	}
+	engine.Register(sagaActions...)
+}
+
+// Saga executes the actions forward in order, each action is recorded before it's executed.
+// If an action fails, or the saga doesn't complete within timeout, the recorded actions are
+// compensated in reverse order by the mq, which retries until they succeed.
+func (engine *Engine) Saga(timeout time.Duration, actions ...SagaAction) error {
+	tcc, err := engine.New(timeout, false, func(data *tccData) {
+		data.Saga = true
+	})
+	if err != nil {
+		return err
+	}
+	var sagaActions = make([]Action, len(actions))
+	for i, action := range actions {
+		sagaActions[i] = sagaAction{action}
+	}
+	_, err = tcc.run(sagaActions)
+	return err
+}
+
+// the actions of a saga have nothing to do in Confirm, so they are marked confirmed in one update.
+func (tcc *TCC) confirmSaga(data *tccData, tx *sql.Tx) (time.Duration, bool, error) {
+	actions := make([]tccAction, len(data.Actions))
+	for i, action := range data.Actions {
+		action.Status = statusConfirmed
+		actions[i] = action
+	}
+	actionsJson, err := json.Marshal(actions)
+	if err != nil {
+		return time.Hour, true, err
+	}
+	canCommit, err := tcc.update(
+		`data = jsonb_set(data, '{Actions}'::text[], `+quote(string(actionsJson))+`::jsonb)`,
+		statusConfirmed, "confirm saga", tx,
+	)
+	if err == nil {
+		data.Actions = actions
+	}
+	return 0, canCommit, err
+}
+
+// sagaAction adapts a SagaAction to an Action: Execute in Try, nothing to do in Confirm,
+// and Compensate in Cancel.
+type sagaAction struct {
+	SagaAction
+}
+
+func (sa sagaAction) Try() error {
+	return sa.Execute()
+}
+
+func (sa sagaAction) Confirm() error {
+	return nil
+}
+
+func (sa sagaAction) Cancel() error {
+	return sa.Compensate()
+}
+
+func (sa sagaAction) MarshalJSON() ([]byte, error) {
+	return json.Marshal(sa.SagaAction)
+}
+
+// the type to marshal or unmarshal an action.
+func actionType(action Action) reflect.Type {
+	if sa, ok := action.(sagaAction); ok {
+		return reflect.TypeOf(sa.SagaAction)
+	}
+	return reflect.TypeOf(action)
+}
+
+// the value to marshal an action.
+func actionValue(action Action) interface{} {
+	if sa, ok := action.(sagaAction); ok {
+		return sa.SagaAction
+	}
+	return action
+}
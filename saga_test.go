@@ -0,0 +1,63 @@
+package tcc
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+func ExampleEngine_Saga() {
+	fmt.Println(tccEngine.Saga(time.Minute, testSagaAction{Step: "a"}, testSagaAction{Step: "b"}))
+	time.Sleep(time.Second)
+	var statuses string
+	if err := testDB.QueryRow(`
+	SELECT string_agg(a->>'Status', ',') FROM sqlmq, jsonb_array_elements(data->'Actions') a
+	WHERE id = (SELECT max(id) FROM sqlmq WHERE data ? 'Saga')`,
+	).Scan(&statuses); err != nil {
+		panic(err)
+	}
+	fmt.Println(statuses)
+	fmt.Println(tccEngine.Saga(time.Minute,
+		testSagaAction{Step: "a"}, testSagaAction{Step: "b", Fail: true}, testSagaAction{Step: "c"},
+	))
+	time.Sleep(time.Second)
+	// Output:
+	// a Execute
+	// b Execute
+	// <nil>
+	// confirmed,confirmed
+	// a Execute
+	// b Execute
+	// b failed
+	// b Compensate
+	// a Compensate
+}
+
+func ExampleEngine_RegisterSaga() {
+	defer func() {
+		fmt.Println(timePrefix.ReplaceAllString(recover().(string), ""))
+	}()
+	tccEngine.RegisterSaga(testSagaAction{})
+	// Output:
+	// action saga-action already registered
+}
+
+type testSagaAction struct {
+	Step string
+	Fail bool
+}
+
+func (ta testSagaAction) Name() string {
+	return "saga-action"
+}
+func (ta testSagaAction) Execute() error {
+	fmt.Println(ta.Step, "Execute")
+	if ta.Fail {
+		return errors.New(ta.Step + " failed")
+	}
+	return nil
+}
+func (ta testSagaAction) Compensate() error {
+	fmt.Println(ta.Step, "Compensate")
+	return nil
+}
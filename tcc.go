@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/lovego/errs"
@@ -18,29 +19,206 @@ const (
 )
 
 type TCC struct {
-	engine *Engine
-	msg    *sqlmq.StdMessage
+	engine  *Engine
+	msg     *sqlmq.StdMessage
+	existed bool
+	ctx     context.Context
+
+	stop       chan struct{} // stop watching the tcc
+	stopOnce   sync.Once
+	pending    pendingTrys // unfinished Trys, including the ones of TryRace continuing in background.
+	txDecision string      // the status recorded by ConfirmTx or CancelTx, see TxCommitted.
+}
+
+// pendingTrys is like sync.WaitGroup, but Add can be called concurrently with Wait at zero,
+// such as a Try starting while the watcher is waiting to cancel.
+type pendingTrys struct {
+	mutex sync.Mutex
+	cond  *sync.Cond
+	n     int
+}
+
+func (p *pendingTrys) Add(delta int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.n += delta; p.n <= 0 && p.cond != nil {
+		p.cond.Broadcast()
+	}
+}
+
+func (p *pendingTrys) Done() {
+	p.Add(-1)
+}
+
+func (p *pendingTrys) Wait() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.cond == nil {
+		p.cond = sync.NewCond(&p.mutex)
+	}
+	for p.n > 0 {
+		p.cond.Wait()
+	}
 }
 
 type tccData struct {
-	Status     string      `json:",omitempty"`
-	Concurrent bool        `json:",omitempty"` // if should do confirm or cancel concurrently.
-	Actions    []tccAction `json:",omitempty"`
+	Status     string            `json:",omitempty"`
+	Concurrent bool              `json:",omitempty"` // if should do confirm or cancel concurrently.
+	Saga       bool              `json:",omitempty"` // if it's a saga, whose actions are not called in the confirm.
+	Xid        string            `json:",omitempty"` // generated id, unique per engine.
+	Key        string            `json:",omitempty"` // business key, unique per engine.
+	Metadata   map[string]string `json:",omitempty"`
+	Deadline   *time.Time        `json:",omitempty"` // Try phase timeout if Engine.Lease is used.
+	Parent     *tccRef           `json:",omitempty"` // parent tcc if it's a child tcc.
+	RevertOf   int64             `json:",omitempty"` // the tcc reverted by this tcc.
+	RevertedBy int64             `json:",omitempty"` // the tcc reverting this tcc.
+	Actions    []tccAction       `json:",omitempty"`
+	Groups     []tccGroup        `json:",omitempty"`
 }
 
-func (tcc *TCC) Try(action Action) error {
-	if err := tcc.engine.checkAction(action); err != nil {
-		return err
+func (tcc *TCC) Id() int64 {
+	return tcc.msg.Id
+}
+
+func (tcc *TCC) Xid() string {
+	return tcc.msg.Data.(*tccData).Xid
+}
+
+func (tcc *TCC) Status() string {
+	return tcc.msg.Data.(*tccData).Status
+}
+
+func (tcc *TCC) Key() string {
+	return tcc.msg.Data.(*tccData).Key
+}
+
+func (tcc *TCC) Metadata() map[string]string {
+	return tcc.msg.Data.(*tccData).Metadata
+}
+
+// Existed reports if the tcc is an existing one returned by New for the same key.
+func (tcc *TCC) Existed() bool {
+	return tcc.existed
+}
+
+// Try the action, if it fails, try the alternatives in order until one succeeds.
+// The first succeeded one is the active action, the failed alternatives are dropped and will be canceled.
+func (tcc *TCC) Try(action Action, alternatives ...Action) error {
+	actions := append([]Action{action}, alternatives...)
+	for _, action := range actions {
+		if err := tcc.engine.checkAction(action); err != nil {
+			return err
+		}
 	}
-	if marshaledAction, err := marshalAction(action); err != nil {
-		return err
-	} else if _, err := tcc.update(`data = jsonb_set(data, '{Actions}'::text[],
-		coalesce(data->'Actions', '[]'::jsonb) || `+quote(string(marshaledAction))+`::jsonb
-	)`, statusTrying, "Try", nil); err != nil {
-		return err
+	var err error
+	for _, action := range actions {
+		if err = tcc.ctx.Err(); err != nil {
+			return err
+		}
+		var index int
+		if index, err = tcc.tryOne(action); err == nil || index < 0 {
+			return err
+		}
+		if len(alternatives) > 0 {
+			if err := tcc.dropAction(index); err != nil {
+				return err
+			}
+		}
 	}
+	return err
+}
 
-	return action.Try()
+// record and try the action, returns the index of the action, or -1 if the action is not recorded.
+func (tcc *TCC) tryOne(action Action) (int, error) {
+	marshaledAction, err := marshalAction(action)
+	if err != nil {
+		return -1, err
+	}
+	index, err := tcc.appendAction(marshaledAction)
+	if err != nil {
+		return -1, err
+	}
+	return index, tcc.invokeTry(action)
+}
+
+func (tcc *TCC) appendAction(marshaledAction []byte) (int, error) {
+	if data := tcc.msg.Data.(*tccData); data.Status != statusTrying {
+		return -1, fmt.Errorf("tcc(%d) is %s, cann't Try", tcc.msg.Id, data.Status)
+	}
+	updateSql := fmt.Sprintf(`
+	UPDATE %s
+	SET data = jsonb_set(data, '{Actions}'::text[],
+		coalesce(data->'Actions', '[]'::jsonb) || %s::jsonb
+	)
+	WHERE id = %d AND queue = '%s' AND data->'Status' = to_jsonb('%s'::text)
+	RETURNING jsonb_array_length(data->'Actions')`,
+		tcc.engine.mqTableName,
+		quote(string(marshaledAction)),
+		tcc.msg.Id, tcc.engine.mqName, statusTrying,
+	)
+	ctx, cancel := sqlTimeout()
+	defer cancel()
+	var length int
+	if err := tcc.engine.sqlmq.DB.QueryRowContext(ctx, updateSql).Scan(&length); err == nil {
+		return length - 1, nil
+	} else if err != sql.ErrNoRows {
+		return -1, errs.Trace(err)
+	}
+	_, err := tcc.statusError("Try", tcc.engine.sqlmq.DB)
+	return -1, err
+}
+
+// Result is the result of a run.
+type Result struct {
+	Id int64
+	// Tried are the actions whose Try succeeded, in order. Actions of pointer type carry their Try outputs.
+	Tried []Action
+	// Failed is the action whose Try failed, nil if all actions are tried successfully.
+	Failed Action
+	// Canceled reports if the cancel of the tcc is recorded successfully after a Try failed.
+	Canceled    bool
+	CancelError error
+}
+
+// try actions in order, then confirm if all succeeded, otherwise cancel.
+func (tcc *TCC) run(actions []Action) (*Result, error) {
+	result, err := tcc.tryAll(actions)
+	if err != nil {
+		return result, err
+	}
+	return result, tcc.Confirm()
+}
+
+// try the actions in order, the tried ones are canceled if any Try fails.
+func (tcc *TCC) tryAll(actions []Action) (*Result, error) {
+	result := &Result{Id: tcc.msg.Id}
+	for _, action := range actions {
+		if err := tcc.Try(action); err != nil {
+			result.Failed = action
+			if result.CancelError = tcc.cancelTried(); result.CancelError != nil {
+				tcc.engine.sqlmq.Logger.Error(result.CancelError)
+			} else {
+				result.Canceled = true
+			}
+			return result, err
+		}
+		result.Tried = append(result.Tried, action)
+	}
+	return result, nil
+}
+
+func (tcc *TCC) cancelTried() error {
+	err := tcc.Cancel()
+	if err != nil && tcc.ctx.Err() != nil {
+		// the tcc may have been canceled by watch() already.
+		if nowStatus, _, err2 := tcc.queryStatus(tcc.engine.sqlmq.DB); err2 == nil &&
+			nowStatus == statusCanceled {
+			tcc.msg.Data.(*tccData).Status = statusCanceled
+			tcc.stopWatching()
+			return nil
+		}
+	}
+	return err
 }
 
 var setTCCStatus = `data = jsonb_set(data, '{Status}'::text[], to_jsonb('%s'::text)), retry_at = now()`
@@ -48,23 +226,232 @@ var setConfirmed = fmt.Sprintf(setTCCStatus, statusConfirmed)
 var setCanceled = fmt.Sprintf(setTCCStatus, statusCanceled)
 
 func (tcc *TCC) Confirm() error {
-	if _, err := tcc.update(setConfirmed, statusTrying, "Confirm", nil); err != nil {
+	return tcc.decide(setConfirmed, statusConfirmed, "Confirm")
+}
+
+func (tcc *TCC) Cancel() error {
+	return tcc.decide(setCanceled, statusCanceled, "Cancel")
+}
+
+// ConfirmTx records the confirm in tx, which must be on the database of the mq,
+// so the tcc is confirmed if and only if tx commits.
+// The tcc in memory is left trying(and its lease is still renewed), because tx may roll back,
+// so call TxCommitted after tx commits.
+func (tcc *TCC) ConfirmTx(tx *sql.Tx) error {
+	if tx == nil {
+		return tcc.Confirm()
+	}
+	return tcc.decideTx(setConfirmed, statusConfirmed, "Confirm", tx)
+}
+
+// CancelTx records the cancel in tx, which must be on the database of the mq,
+// so the tcc is canceled if and only if tx commits.
+// Like ConfirmTx, the tcc in memory is left trying.
+func (tcc *TCC) CancelTx(tx *sql.Tx) error {
+	if tx == nil {
+		return tcc.Cancel()
+	}
+	return tcc.decideTx(setCanceled, statusCanceled, "Cancel", tx)
+}
+
+// TxCommitted should be called after the tx passed to ConfirmTx or CancelTx commits.
+// It sets the status of the tcc in memory, stops watching the tcc, and wakes the mq consumer to
+// handle the decision at once, instead of on its next idle wake. Without it, the watcher exits
+// only on the next heartbeat(if Engine.Lease is used) or when the context of the tcc is done.
+func (tcc *TCC) TxCommitted() {
+	if tcc.txDecision == "" {
+		return
+	}
+	tcc.msg.Data.(*tccData).Status = tcc.txDecision
+	tcc.txDecision = ""
+	tcc.stopWatching()
+	tcc.engine.sqlmq.NotifyConsumeAt(time.Now(), "tcc.TxCommitted")
+}
+
+func (tcc *TCC) decide(set, status, method string) error {
+	tcc.pending.Wait()
+	if _, err := tcc.update(set, statusTrying, method, nil); err != nil {
 		return err
 	}
-	tcc.msg.Data.(*tccData).Status = statusConfirmed
-	tcc.engine.sqlmq.NotifyConsumeAt(time.Now(), "tcc.Confirm")
+	tcc.msg.Data.(*tccData).Status = status
+	tcc.stopWatching()
+	tcc.engine.sqlmq.NotifyConsumeAt(time.Now(), "tcc."+method)
 	return nil
 }
 
-func (tcc *TCC) Cancel() error {
-	if _, err := tcc.update(setCanceled, statusTrying, "Cancel", nil); err != nil {
+func (tcc *TCC) decideTx(set, status, method string, tx *sql.Tx) error {
+	tcc.pending.Wait()
+	if _, err := tcc.update(set, statusTrying, method, tx); err != nil {
 		return err
 	}
-	tcc.msg.Data.(*tccData).Status = statusCanceled
-	tcc.engine.sqlmq.NotifyConsumeAt(time.Now(), "tcc.Cancel")
+	tcc.txDecision = status
 	return nil
 }
 
+// Extend pushes the Try phase timeout of the tcc forward by d(positive). It's allowed only while the tcc is
+// trying, and the total lifetime of the tcc can't exceed Engine.MaxLifetime if it's set.
+func (tcc *TCC) Extend(d time.Duration) error {
+	if d <= 0 {
+		return fmt.Errorf("tcc(%d) cann't Extend by non-positive duration %v", tcc.msg.Id, d)
+	}
+	data := tcc.msg.Data.(*tccData)
+	if data.Status != statusTrying {
+		return fmt.Errorf("tcc(%d) is %s, cann't Extend", tcc.msg.Id, data.Status)
+	}
+	interval := fmt.Sprintf("'%d microseconds'::interval", d.Microseconds())
+	deadline, set := "retry_at", "retry_at = retry_at + "+interval
+	if data.Deadline != nil {
+		deadline = "(data->>'Deadline')::timestamptz"
+		// retry_at may be pinned at the old deadline by heartbeats, so it's renewed too.
+		retryAt := deadline + " + " + interval
+		if lease := tcc.engine.Lease; lease > 0 {
+			retryAt = fmt.Sprintf(
+				"least(%s, now() + '%d microseconds'::interval)", retryAt, lease.Microseconds(),
+			)
+		}
+		set = fmt.Sprintf(
+			"data = jsonb_set(data, '{Deadline}'::text[], to_jsonb(%s + %s)), retry_at = %s",
+			deadline, interval, retryAt,
+		)
+	}
+	var maxLifetimeCond string
+	if maxLifetime := tcc.engine.MaxLifetime; maxLifetime > 0 {
+		maxLifetimeCond = fmt.Sprintf(
+			"AND %s + %s <= created_at + '%d microseconds'::interval",
+			deadline, interval, maxLifetime.Microseconds(),
+		)
+	}
+	updateSql := fmt.Sprintf(`
+	UPDATE %s
+	SET %s
+	WHERE id = %d AND queue = '%s' AND data->'Status' = to_jsonb('%s'::text) %s
+	RETURNING %s, retry_at`,
+		tcc.engine.mqTableName,
+		set,
+		tcc.msg.Id, tcc.engine.mqName, statusTrying, maxLifetimeCond,
+		deadline,
+	)
+	ctx, cancel := sqlTimeout()
+	defer cancel()
+	var newDeadline, retryAt time.Time
+	if err := tcc.engine.sqlmq.DB.QueryRowContext(ctx, updateSql).Scan(&newDeadline, &retryAt); err == nil {
+		if data.Deadline != nil {
+			data.Deadline = &newDeadline
+		}
+		tcc.msg.RetryAt = retryAt
+		return nil
+	} else if err != sql.ErrNoRows {
+		return errs.Trace(err)
+	}
+	if nowStatus, _, err := tcc.queryStatus(tcc.engine.sqlmq.DB); err != nil {
+		return err
+	} else if nowStatus != statusTrying {
+		return fmt.Errorf("tcc(%d) is %s, cann't Extend", tcc.msg.Id, nowStatus)
+	}
+	return fmt.Errorf("tcc(%d) cann't Extend beyond max lifetime %v", tcc.msg.Id, tcc.engine.MaxLifetime)
+}
+
+// watch the tcc until it's confirmed or canceled: renew the lease by heartbeat,
+// and cancel the tcc at once if its context is done.
+func (tcc *TCC) watch() {
+	var heartbeat <-chan time.Time
+	if tcc.engine.Lease > 0 {
+		ticker := time.NewTicker(tcc.engine.Lease / 3)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+	ctxDone, tried := tcc.ctx.Done(), make(chan struct{})
+	for {
+		select {
+		case <-tcc.stop:
+			return
+		case <-ctxDone:
+			// the participants must not be canceled before their Trys return,
+			// and the lease is still renewed while waiting.
+			ctxDone = nil
+			go func() {
+				tcc.pending.Wait()
+				close(tried)
+			}()
+		case <-tried:
+			if canceled, err := tcc.updateTrying(setCanceled); err != nil {
+				tcc.engine.sqlmq.Logger.Error(err)
+			} else if canceled {
+				tcc.engine.sqlmq.NotifyConsumeAt(time.Now(), "tcc context done")
+			}
+			return
+		case <-heartbeat:
+			if renewed, err := tcc.renewLease(); err != nil {
+				tcc.engine.sqlmq.Logger.Error(err)
+			} else if !renewed {
+				return
+			}
+		}
+	}
+}
+
+// watch the tcc if Engine.Lease is used or its context can be done.
+func (tcc *TCC) startWatching() {
+	if tcc.engine.Lease > 0 || tcc.ctx.Done() != nil {
+		tcc.stop = make(chan struct{})
+		go tcc.watch()
+	}
+}
+
+func (tcc *TCC) stopWatching() {
+	if tcc.stop != nil {
+		tcc.stopOnce.Do(func() {
+			close(tcc.stop)
+		})
+	}
+}
+
+// renew the lease if the tcc is still trying. The row locked by others(such as the tx of ConfirmTx
+// or CancelTx) is skipped instead of waited, and reported as renewed if it's still trying.
+func (tcc *TCC) renewLease() (bool, error) {
+	updateSql := fmt.Sprintf(`
+	UPDATE %s
+	SET retry_at = least((data->>'Deadline')::timestamptz, now() + '%d microseconds'::interval)
+	WHERE id = (SELECT id FROM %s WHERE id = %d FOR UPDATE SKIP LOCKED)
+		AND queue = '%s' AND data->'Status' = to_jsonb('%s'::text)`,
+		tcc.engine.mqTableName, tcc.engine.Lease.Microseconds(),
+		tcc.engine.mqTableName, tcc.msg.Id, tcc.engine.mqName, statusTrying,
+	)
+	ctx, cancel := sqlTimeout()
+	defer cancel()
+	if result, err := tcc.engine.sqlmq.DB.ExecContext(ctx, updateSql); err != nil {
+		return false, errs.Trace(err)
+	} else if n, err := result.RowsAffected(); err != nil {
+		return false, errs.Trace(err)
+	} else if n == 1 {
+		return true, nil
+	}
+	nowStatus, _, err := tcc.queryStatus(tcc.engine.sqlmq.DB)
+	return nowStatus == statusTrying, err
+}
+
+// update the tcc if it's still trying, without checking the status in memory,
+// so it can run concurrently with the methods of the tcc.
+func (tcc *TCC) updateTrying(set string) (bool, error) {
+	updateSql := fmt.Sprintf(`
+	UPDATE %s
+	SET %s
+	WHERE id = %d AND queue = '%s' AND data->'Status' = to_jsonb('%s'::text)`,
+		tcc.engine.mqTableName,
+		set,
+		tcc.msg.Id, tcc.engine.mqName, statusTrying,
+	)
+	ctx, cancel := sqlTimeout()
+	defer cancel()
+	if result, err := tcc.engine.sqlmq.DB.ExecContext(ctx, updateSql); err != nil {
+		return false, errs.Trace(err)
+	} else if n, err := result.RowsAffected(); err != nil {
+		return false, errs.Trace(err)
+	} else {
+		return n == 1, nil
+	}
+}
+
 func (tcc *TCC) update(set, assertStatus, method string, db sqlmq.DBOrTx) (bool, error) {
 	if data := tcc.msg.Data.(*tccData); data.Status != assertStatus {
 		return true, fmt.Errorf("tcc(%d) is %s, cann't %s", tcc.msg.Id, data.Status, method)
@@ -96,6 +483,14 @@ func (tcc *TCC) update(set, assertStatus, method string, db sqlmq.DBOrTx) (bool,
 }
 
 func (tcc *TCC) statusError(method string, db sqlmq.DBOrTx) (bool, error) {
+	nowStatus, canCommit, err := tcc.queryStatus(db)
+	if err != nil {
+		return canCommit, err
+	}
+	return true, fmt.Errorf("tcc(%d) is %s, cann't %s", tcc.msg.Id, nowStatus, method)
+}
+
+func (tcc *TCC) queryStatus(db sqlmq.DBOrTx) (string, bool, error) {
 	querySql := fmt.Sprintf(`
 	SELECT data->'Status'#>>'{}' as status
 	FROM %s
@@ -108,11 +503,11 @@ func (tcc *TCC) statusError(method string, db sqlmq.DBOrTx) (bool, error) {
 	defer cancel()
 	if err := db.QueryRowContext(ctx, querySql).Scan(&nowStatus); err != nil {
 		if err == sql.ErrNoRows {
-			return true, fmt.Errorf("tcc(%d) not exists", tcc.msg.Id)
+			return "", true, fmt.Errorf("tcc(%d) not exists", tcc.msg.Id)
 		}
-		return false, errs.Trace(err)
+		return "", false, errs.Trace(err)
 	}
-	return true, fmt.Errorf("tcc(%d) is %s, cann't %s", tcc.msg.Id, nowStatus, method)
+	return nowStatus, true, nil
 }
 
 func sqlTimeout() (context.Context, func()) {
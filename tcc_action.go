@@ -1,6 +1,7 @@
 package tcc
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
@@ -8,44 +9,117 @@ import (
 )
 
 type tccAction struct {
-	Name   string `json:",omitempty"`
-	Raw    json.RawMessage
-	Status string `json:",omitempty"`
+	Name    string `json:",omitempty"`
+	Raw     json.RawMessage
+	Codec   string `json:",omitempty"` // empty for json, otherwise Raw is a base64 string of the payload.
+	Version int    `json:",omitempty"` // the schema version of the payload, see VersionedAction.
+	Status  string `json:",omitempty"`
+	Dropped bool   `json:",omitempty"` // an alternative whose Try failed, it's canceled even if the tcc is confirmed.
 }
 
 func marshalAction(action Action) ([]byte, error) {
-	actionJson, err := json.Marshal(action)
-	if err != nil {
-		return nil, err
+	ta := tccAction{Name: action.Name(), Version: versionOf(action)}
+	if codecName := codecOf(action); codecName == codecJSON {
+		actionJson, err := json.Marshal(action)
+		if err != nil {
+			return nil, err
+		}
+		ta.Raw = json.RawMessage(actionJson)
+	} else {
+		codec, err := getCodec(codecName)
+		if err != nil {
+			return nil, err
+		}
+		payload, err := codec.Marshal(actionValue(action))
+		if err != nil {
+			return nil, err
+		}
+		if ta.Raw, err = json.Marshal(payload); err != nil {
+			return nil, err
+		}
+		ta.Codec = codecName
+	}
+	return json.Marshal(ta)
+}
+
+// the payload bytes in the codec of the action.
+func (ta tccAction) payload() ([]byte, error) {
+	if ta.Codec == "" {
+		return ta.Raw, nil
+	}
+	var payload []byte
+	err := json.Unmarshal(ta.Raw, &payload)
+	return payload, err
+}
+
+// the status an action should reach when the tcc is confirmed or canceled.
+func (data *tccData) actionStatus(actionIndex int) string {
+	if data.Status != statusConfirmed || data.Actions[actionIndex].Dropped || data.excluded(actionIndex) {
+		return statusCanceled
+	}
+	return statusConfirmed
+}
+
+func (ta tccAction) finish(tcc *TCC, tx *sql.Tx, actionIndex int, status string) (
+	time.Duration, bool, error,
+) {
+	if status == statusConfirmed {
+		return ta.confirm(tcc, tx, actionIndex)
 	}
-	return json.Marshal(tccAction{
-		Name: action.Name(),
-		Raw:  json.RawMessage(actionJson),
-	})
+	return ta.cancel(tcc, tx, actionIndex)
 }
 
 func (ta tccAction) confirm(tcc *TCC, tx *sql.Tx, actionIndex int) (time.Duration, bool, error) {
-	action, err := tcc.engine.unmarshalAction(ta.Name, ta.Raw)
+	action, err := tcc.engine.unmarshalAction(ta)
 	if err != nil {
 		return time.Hour, true, err
 	}
-	if err := action.Confirm(); err != nil {
+	if local, ok := action.(LocalAction); ok {
+		if err := local.ConfirmTx(tx); err != nil {
+			return 0, false, err
+		}
+	} else if err := confirmAction(tcc.context(), action); err != nil {
 		return 0, true, err
 	}
 	return setActionStatus(tcc, tx, actionIndex, statusConfirmed, "confirm action")
 }
 
 func (ta tccAction) cancel(tcc *TCC, tx *sql.Tx, actionIndex int) (time.Duration, bool, error) {
-	action, err := tcc.engine.unmarshalAction(ta.Name, ta.Raw)
+	action, err := tcc.engine.unmarshalAction(ta)
 	if err != nil {
 		return time.Hour, true, err
 	}
-	if err := action.Cancel(); err != nil {
+	if local, ok := action.(LocalAction); ok {
+		if err := local.CancelTx(tx); err != nil {
+			return 0, false, err
+		}
+	} else if err := cancelAction(tcc.context(), action); err != nil {
 		return 0, true, err
 	}
 	return setActionStatus(tcc, tx, actionIndex, statusCanceled, "cancel action")
 }
 
+func tryAction(ctx context.Context, action Action) error {
+	if a, ok := action.(ContextAction); ok {
+		return a.TryContext(ctx)
+	}
+	return action.Try()
+}
+
+func confirmAction(ctx context.Context, action Action) error {
+	if a, ok := action.(ContextAction); ok {
+		return a.ConfirmContext(ctx)
+	}
+	return action.Confirm()
+}
+
+func cancelAction(ctx context.Context, action Action) error {
+	if a, ok := action.(ContextAction); ok {
+		return a.CancelContext(ctx)
+	}
+	return action.Cancel()
+}
+
 func setActionStatus(
 	tcc *TCC, tx *sql.Tx, actionIndex int, status, method string,
 ) (time.Duration, bool, error) {
@@ -53,6 +127,18 @@ func setActionStatus(
 		`data = jsonb_set(data, '{Actions,%d,Status}'::text[], to_jsonb('%s'::text))`,
 		actionIndex, status,
 	)
-	canCommit, err := tcc.update(setSql, status, method, tx)
+	data := tcc.msg.Data.(*tccData)
+	canCommit, err := tcc.update(setSql, data.Status, method, tx)
+	if err == nil {
+		data.Actions[actionIndex].Status = status
+	}
 	return 0, canCommit, err
 }
+
+func (tcc *TCC) dropAction(actionIndex int) error {
+	_, err := tcc.update(
+		fmt.Sprintf(`data = jsonb_set(data, '{Actions,%d,Dropped}'::text[], 'true'::jsonb)`, actionIndex),
+		statusTrying, "drop action", nil,
+	)
+	return err
+}
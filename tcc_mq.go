@@ -3,6 +3,7 @@ package tcc
 import (
 	"database/sql"
 	"errors"
+	"fmt"
 	"strings"
 	"sync"
 	"time"
@@ -11,6 +12,12 @@ import (
 // func for mq handling.
 func (tcc *TCC) confirmOrCancel(tx *sql.Tx) (time.Duration, bool, error) {
 	data := tcc.msg.Data.(*tccData)
+	switch data.Status {
+	case statusReverted:
+		return tcc.revertSerially(data, tx)
+	case statusConfirmFailed:
+		return tcc.compensate(data, tx)
+	}
 	if data.Status != statusConfirmed && data.Status != statusCanceled {
 		// cancel tcc if trying timeout
 		data.Status = statusCanceled
@@ -19,19 +26,27 @@ func (tcc *TCC) confirmOrCancel(tx *sql.Tx) (time.Duration, bool, error) {
 		}
 	}
 
-	confirm := data.Status == statusConfirmed
-	if data.Concurrent {
-		if confirm {
-			return tcc.confirmConcurrently(data, tx)
+	if data.Status == statusConfirmed && data.Saga {
+		return tcc.confirmSaga(data, tx)
+	}
+	if data.Status == statusConfirmed {
+		var retryAfter time.Duration
+		var canCommit bool
+		var err error
+		if data.Concurrent {
+			retryAfter, canCommit, err = tcc.confirmConcurrently(data, tx)
 		} else {
-			return tcc.cancelConcurrently(data, tx)
+			retryAfter, canCommit, err = tcc.confirmSerially(data, tx)
 		}
-	} else {
-		if confirm {
-			return tcc.confirmSerially(data, tx)
-		} else {
-			return tcc.cancelSerially(data, tx)
+		if err != nil && canCommit && tcc.confirmGivenUp(err) {
+			return tcc.escalateConfirmFailure(data, tx)
 		}
+		return retryAfter, canCommit, err
+	}
+	if data.Concurrent {
+		return tcc.cancelConcurrently(data, tx)
+	} else {
+		return tcc.cancelSerially(data, tx)
 	}
 }
 
@@ -39,26 +54,34 @@ func (tcc *TCC) confirmConcurrently(data *tccData, tx *sql.Tx) (time.Duration, b
 	var retryAfter time.Duration
 	var canCommit = true
 	var errs []string
+	var permanent bool
+	var mutex sync.Mutex
 	var wg sync.WaitGroup
 	for i, action := range data.Actions {
-		if action.Status != statusConfirmed {
+		if status := data.actionStatus(i); action.Status != status {
 			wg.Add(1)
-			go func(action tccAction, i int) {
-				if _retryAfter, _canCommit, err := action.confirm(tcc, tx, i); err != nil {
+			go func(action tccAction, i int, status string) {
+				if _retryAfter, _canCommit, err := action.finish(tcc, tx, i, status); err != nil {
+					mutex.Lock()
 					if _retryAfter > retryAfter {
 						retryAfter = _retryAfter
 					}
 					canCommit = canCommit && _canCommit
 					errs = append(errs, action.Name+": "+err.Error())
+					permanent = permanent || isPermanent(err)
+					mutex.Unlock()
 				}
 				wg.Done()
-			}(action, i)
+			}(action, i, status)
 		}
 	}
 	wg.Wait()
 	if len(errs) == 0 {
 		return 0, true, nil
 	}
+	if permanent {
+		return retryAfter, canCommit, Permanent(errors.New(strings.Join(errs, "; ")))
+	}
 	return retryAfter, canCommit, errors.New(strings.Join(errs, "; "))
 }
 
@@ -91,9 +114,9 @@ func (tcc *TCC) cancelConcurrently(data *tccData, tx *sql.Tx) (time.Duration, bo
 
 func (tcc *TCC) confirmSerially(data *tccData, tx *sql.Tx) (time.Duration, bool, error) {
 	for i, action := range data.Actions {
-		if action.Status != statusConfirmed {
-			if retryAfter, canCommit, err := action.confirm(tcc, tx, i); err != nil {
-				return retryAfter, canCommit, errors.New(action.Name + ": " + err.Error())
+		if status := data.actionStatus(i); action.Status != status {
+			if retryAfter, canCommit, err := action.finish(tcc, tx, i, status); err != nil {
+				return retryAfter, canCommit, fmt.Errorf("%s: %w", action.Name, err)
 			}
 		}
 	}
@@ -1,6 +1,7 @@
 package tcc
 
 import (
+	"context"
 	"fmt"
 	"regexp"
 	"time"
@@ -25,6 +26,33 @@ func ExampleTCC_Try() {
 	// tcc(-9) not exists
 }
 
+func ExampleTCC_Try_alternatives() {
+	tcc, err := tccEngine.New(time.Minute, false)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(tcc.Try(testAction3{}, testAction1{}))
+	fmt.Println(tcc.Confirm())
+	fmt.Println(tcc.Wait(context.Background()))
+	got, err := tccEngine.Get(tcc.Id())
+	if err != nil {
+		panic(err)
+	}
+	for _, action := range got.msg.Data.(*tccData).Actions {
+		fmt.Println(action.Name, action.Status, action.Dropped)
+	}
+	// Output:
+	// action3 Try
+	// action1 Try
+	// <nil>
+	// <nil>
+	// action3 Cancel
+	// action1 Confirm
+	// confirmed <nil>
+	// action3 canceled true
+	// action1 confirmed false
+}
+
 func ExampleTCC_Confirm() {
 	tcc, err := tccEngine.New(time.Minute, false)
 	if err != nil {
@@ -51,6 +79,141 @@ func ExampleTCC_Cancel() {
 	// tcc(1) is confirmed, cann't Cancel
 }
 
+func ExampleTCC_ConfirmTx() {
+	tcc, err := tccEngine.New(time.Minute, false)
+	if err != nil {
+		panic(err)
+	}
+	tx, err := testDB.Begin()
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(tcc.ConfirmTx(tx))
+	fmt.Println(tx.Rollback())
+	got, err := tccEngine.Get(tcc.Id())
+	fmt.Println(got.Status(), err)
+	fmt.Println(tcc.Status())
+	fmt.Println(tcc.Cancel())
+
+	tcc, err = tccEngine.New(time.Minute, false)
+	if err != nil {
+		panic(err)
+	}
+	tx, err = testDB.Begin()
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(tcc.CancelTx(tx))
+	fmt.Println(tx.Commit())
+	tcc.TxCommitted()
+	got, err = tccEngine.Get(tcc.Id())
+	fmt.Println(got.Status(), tcc.Status(), err)
+	// Output:
+	// <nil>
+	// <nil>
+	// trying <nil>
+	// trying
+	// <nil>
+	// <nil>
+	// <nil>
+	// canceled canceled <nil>
+}
+
+func ExampleLocalAction() {
+	if _, err := testDB.Exec(`
+	DROP TABLE IF EXISTS tcc_local;
+	CREATE TABLE tcc_local (value text);
+	`); err != nil {
+		panic(err)
+	}
+	tcc, err := tccEngine.New(time.Minute, false)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(tcc.Try(testLocalAction{Value: "a"}))
+	fmt.Println(tcc.Try(testLocalAction{Value: "b"}))
+	fmt.Println(tcc.Cancel())
+	fmt.Println(tcc.Wait(context.Background()))
+
+	rows, err := testDB.Query("SELECT value FROM tcc_local")
+	if err != nil {
+		panic(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			panic(err)
+		}
+		fmt.Println(value)
+	}
+	// Output:
+	// <nil>
+	// <nil>
+	// <nil>
+	// canceled <nil>
+	// b canceled
+	// a canceled
+}
+
+func ExampleTCC_Extend() {
+	tcc, err := tccEngine.New(time.Minute, false)
+	if err != nil {
+		panic(err)
+	}
+	retryAt := tcc.msg.RetryAt
+	fmt.Println(tcc.Extend(time.Minute))
+	fmt.Println(tcc.msg.RetryAt.Sub(retryAt).Round(time.Second))
+
+	tccEngine.MaxLifetime = 2 * time.Minute
+	defer func() {
+		tccEngine.MaxLifetime = 0
+	}()
+	fmt.Println(tccId.ReplaceAllString(tcc.Extend(time.Minute).Error(), "tcc(1)"))
+	fmt.Println(tccId.ReplaceAllString(tcc.Extend(0).Error(), "tcc(1)"))
+	fmt.Println(tccId.ReplaceAllString(tcc.Extend(-time.Minute).Error(), "tcc(1)"))
+
+	fmt.Println(tcc.Cancel())
+	fmt.Println(tccId.ReplaceAllString(tcc.Extend(time.Minute).Error(), "tcc(1)"))
+	// Output:
+	// <nil>
+	// 1m0s
+	// tcc(1) cann't Extend beyond max lifetime 2m0s
+	// tcc(1) cann't Extend by non-positive duration 0s
+	// tcc(1) cann't Extend by non-positive duration -1m0s
+	// <nil>
+	// tcc(1) is canceled, cann't Extend
+}
+
+func ExampleTCC_watch() {
+	tccEngine.Lease = time.Second
+	defer func() {
+		tccEngine.Lease = 0
+	}()
+	tcc, err := tccEngine.New(time.Minute, false)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(
+		tcc.msg.RetryAt.Sub(tcc.msg.CreatedAt), tcc.msg.Data.(*tccData).Deadline.Sub(tcc.msg.CreatedAt),
+	)
+	time.Sleep(1500 * time.Millisecond)
+	fmt.Println(tcc.Extend(time.Minute))
+	// retry_at is renewed by a lease instead of the deadline.
+	fmt.Println(time.Until(tcc.msg.RetryAt) <= time.Second)
+	got, err := tccEngine.Get(tcc.Id())
+	fmt.Println(got.Status(), got.msg.Data.(*tccData).Deadline.Sub(got.msg.CreatedAt).Round(time.Second), err)
+
+	tcc.stopWatching() // the lease lapses as if the process crashed.
+	fmt.Println(tcc.Wait(context.Background()))
+	// Output:
+	// 1s 1m0s
+	// <nil>
+	// true
+	// trying 2m0s <nil>
+	// canceled <nil>
+}
+
 func ExampleTCC_update() {
 	tcc, err := tccEngine.New(time.Minute, false)
 	if err != nil {
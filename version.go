@@ -0,0 +1,75 @@
+package tcc
+
+import (
+	"fmt"
+	"time"
+)
+
+// VersionedAction is an action with a schema version of its payload, which should be increased
+// when the payload changes incompatibly. Actions without Version are of version 0.
+type VersionedAction interface {
+	Version() int
+}
+
+func versionOf(action Action) int {
+	if a, ok := actionValue(action).(VersionedAction); ok {
+		return a.Version()
+	}
+	return 0
+}
+
+// RegisterUpgrade registers a function to upgrade the payload(in the codec of the action)
+// of the named action from fromVersion to fromVersion+1. When an action of an older version is
+// unmarshaled, the upgrades are applied in chain up to the registered version of the action,
+// so in-flight tccs can be confirmed or canceled after the action changed. It's an error if
+// an upgrade in the chain is missing, or the action is newer than the registered version.
+func (engine *Engine) RegisterUpgrade(
+	name string, fromVersion int, upgrade func(payload []byte) ([]byte, error),
+) {
+	engine.mutex.Lock()
+	defer engine.mutex.Unlock()
+	if engine.upgrades == nil {
+		engine.upgrades = make(map[string]map[int]func([]byte) ([]byte, error))
+	}
+	if engine.upgrades[name] == nil {
+		engine.upgrades[name] = make(map[int]func([]byte) ([]byte, error))
+	}
+	if _, ok := engine.upgrades[name][fromVersion]; ok {
+		panic(fmt.Sprintf("%s upgrade of action %s from version %d already registered",
+			time.Now().Format(time.RFC3339Nano), name, fromVersion))
+	}
+	engine.upgrades[name][fromVersion] = upgrade
+}
+
+// the upgrades from version to the current version, must be called with engine.mutex locked.
+func (engine *Engine) upgradesOf(name string, version, current int) (
+	[]func([]byte) ([]byte, error), error,
+) {
+	if version > current {
+		return nil, fmt.Errorf("action %s of version %d is newer than the registered version %d",
+			name, version, current)
+	}
+	var upgrades []func([]byte) ([]byte, error)
+	for ; version < current; version++ {
+		upgrade := engine.upgrades[name][version]
+		if upgrade == nil {
+			return nil, fmt.Errorf("action %s has no upgrade from version %d to %d",
+				name, version, version+1)
+		}
+		upgrades = append(upgrades, upgrade)
+	}
+	return upgrades, nil
+}
+
+func upgradePayload(upgrades []func([]byte) ([]byte, error), version int, payload []byte) (
+	[]byte, error,
+) {
+	for _, upgrade := range upgrades {
+		var err error
+		if payload, err = upgrade(payload); err != nil {
+			return nil, fmt.Errorf("upgrade from version %d: %w", version, err)
+		}
+		version++
+	}
+	return payload, nil
+}
@@ -0,0 +1,135 @@
+package tcc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+func ExampleEngine_RegisterUpgrade() {
+	tcc, err := tccEngine.New(time.Minute, false)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(tcc.Try(testVersionAction{Cents: 100}))
+	// rewrite the action as it was recorded before the deploy of version 1.
+	if _, err := testDB.Exec(`UPDATE sqlmq SET data = jsonb_set(data, '{Actions,0}',
+		'{"Name": "version-action", "Raw": {"Yuan": 5}}') WHERE id = $1`, tcc.Id(),
+	); err != nil {
+		panic(err)
+	}
+	fmt.Println(tcc.Confirm())
+	fmt.Println(tcc.Wait(context.Background()))
+
+	defer func() {
+		fmt.Println(timePrefix.ReplaceAllString(recover().(string), ""))
+	}()
+	tccEngine.RegisterUpgrade("version-action", 0, nil)
+	// Output:
+	// <nil>
+	// <nil>
+	// version-action Confirm 500
+	// confirmed <nil>
+	// upgrade of action version-action from version 0 already registered
+}
+
+// testVersionAction of version 1 records cents instead of yuan of version 0.
+type testVersionAction struct {
+	Cents int
+}
+
+func (ta testVersionAction) Name() string {
+	return "version-action"
+}
+func (ta testVersionAction) Version() int {
+	return 1
+}
+func (ta testVersionAction) Try() error {
+	return nil
+}
+func (ta testVersionAction) Confirm() error {
+	fmt.Println("version-action Confirm", ta.Cents)
+	return nil
+}
+func (ta testVersionAction) Cancel() error {
+	return nil
+}
+
+func upgradeTestVersionAction(payload []byte) ([]byte, error) {
+	var v0 struct{ Yuan int }
+	if err := json.Unmarshal(payload, &v0); err != nil {
+		return nil, err
+	}
+	return json.Marshal(testVersionAction{Cents: v0.Yuan * 100})
+}
+
+func ExampleEngine_RegisterUpgrade_missing() {
+	// only the upgrade from version 0 to 1 is registered.
+	_, err := tccEngine.unmarshalAction(tccAction{Name: "version2-action", Raw: []byte(`{}`)})
+	fmt.Println(err)
+	_, err = tccEngine.unmarshalAction(tccAction{Name: "version2-action", Raw: []byte(`{}`), Version: 3})
+	fmt.Println(err)
+	action, err := tccEngine.unmarshalAction(
+		tccAction{Name: "version2-action", Raw: []byte(`{"Cents": 1}`), Version: 2},
+	)
+	fmt.Println(action, err)
+	// Output:
+	// action version2-action has no upgrade from version 1 to 2
+	// action version2-action of version 3 is newer than the registered version 2
+	// {1} <nil>
+}
+
+func ExampleEngine_RegisterFuncVersion() {
+	tccEngine.RegisterFuncVersion("version3-action", 3, func(raw []byte) (Action, error) {
+		var action testVersion3Action
+		err := json.Unmarshal(raw, &action)
+		return action, err
+	})
+	tcc, err := tccEngine.New(time.Minute, false)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(tcc.Try(testVersion3Action{}))
+	fmt.Println(tcc.Cancel())
+	// Output:
+	// action version3-action has been registered with version 3, but tried with version 0
+	// <nil>
+}
+
+// testVersion3Action has no Version method, so it's of version 0.
+type testVersion3Action struct {
+}
+
+func (ta testVersion3Action) Name() string {
+	return "version3-action"
+}
+func (ta testVersion3Action) Try() error {
+	return nil
+}
+func (ta testVersion3Action) Confirm() error {
+	return nil
+}
+func (ta testVersion3Action) Cancel() error {
+	return nil
+}
+
+type testVersion2Action struct {
+	Cents int
+}
+
+func (ta testVersion2Action) Name() string {
+	return "version2-action"
+}
+func (ta testVersion2Action) Version() int {
+	return 2
+}
+func (ta testVersion2Action) Try() error {
+	return nil
+}
+func (ta testVersion2Action) Confirm() error {
+	return nil
+}
+func (ta testVersion2Action) Cancel() error {
+	return nil
+}
@@ -0,0 +1,80 @@
+package tcc
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// WithXid sets the Xid of the tcc, it overrides the Engine.XidGenerator.
+// The Xid must be unique per engine.
+func WithXid(xid string) Option {
+	return func(data *tccData) {
+		data.Xid = xid
+	}
+}
+
+// UUIDv7 generates an UUID version 7 string, it can be used as an Engine.XidGenerator.
+func UUIDv7() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[6:]); err != nil {
+		return "", err
+	}
+	ms := uint64(time.Now().UnixNano() / int64(time.Millisecond))
+	for i := 5; i >= 0; i-- {
+		b[i] = byte(ms)
+		ms >>= 8
+	}
+	b[6] = b[6]&0x0f | 0x70 // version 7
+	b[8] = b[8]&0x3f | 0x80 // variant 10
+
+	var s [36]byte
+	hex.Encode(s[0:8], b[0:4])
+	s[8] = '-'
+	hex.Encode(s[9:13], b[4:6])
+	s[13] = '-'
+	hex.Encode(s[14:18], b[6:8])
+	s[18] = '-'
+	hex.Encode(s[19:23], b[8:10])
+	s[23] = '-'
+	hex.Encode(s[24:], b[10:])
+	return string(s[:]), nil
+}
+
+// NewXid generates a Xid by Engine.XidGenerator, so it can be used before the tcc is created.
+func (engine *Engine) NewXid() (string, error) {
+	if engine.XidGenerator == nil {
+		return "", fmt.Errorf("tcc engine %s has no XidGenerator", engine.mqName)
+	}
+	return engine.XidGenerator()
+}
+
+func (engine *Engine) idCondition(id interface{}) (string, error) {
+	switch v := id.(type) {
+	case int64:
+		return fmt.Sprintf("id = %d", v), nil
+	case int:
+		return fmt.Sprintf("id = %d", v), nil
+	case string:
+		// "data ? 'Xid'" is the predicate of the partial index.
+		return "data ? 'Xid' AND data->>'Xid' = " + quote(v), nil
+	default:
+		return "", fmt.Errorf("invalid tcc id: %#v", id)
+	}
+}
+
+// resolve the numeric id of the tcc.
+func (engine *Engine) resolveId(id interface{}) (int64, error) {
+	switch v := id.(type) {
+	case int64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	}
+	tcc, err := engine.Get(id)
+	if err != nil {
+		return 0, err
+	}
+	return tcc.msg.Id, nil
+}
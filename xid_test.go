@@ -0,0 +1,72 @@
+package tcc
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+func ExampleUUIDv7() {
+	uuid, err := UUIDv7()
+	fmt.Println(err)
+	fmt.Println(regexp.MustCompile(
+		`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`,
+	).MatchString(uuid))
+	// Output:
+	// <nil>
+	// true
+}
+
+func ExampleWithXid() {
+	tcc, err := tccEngine.New(time.Minute, false, WithXid("xid-1"))
+	if err != nil {
+		panic(err)
+	}
+	got, err := tccEngine.Get("xid-1")
+	fmt.Println(got.Id() == tcc.Id(), got.Xid(), err)
+	fmt.Println(tcc.Confirm())
+	fmt.Println(tccEngine.Wait(context.Background(), "xid-1"))
+
+	_, err = tccEngine.New(time.Minute, false, WithXid("xid-1"))
+	fmt.Println(isUniqueViolation(err, tccEngine.indexName("xid")))
+	// a duplicate Xid is not taken as an existing tcc of the key.
+	_, err = tccEngine.New(time.Minute, false, WithKey("xid-order-1"), WithXid("xid-1"))
+	fmt.Println(isUniqueViolation(err, tccEngine.indexName("xid")))
+	_, err = tccEngine.Get("xid-2")
+	fmt.Println(err)
+	_, err = tccEngine.Get(1.5)
+	fmt.Println(err)
+	// Output:
+	// true xid-1 <nil>
+	// <nil>
+	// confirmed <nil>
+	// true
+	// true
+	// tcc(xid-2) not exists
+	// invalid tcc id: 1.5
+}
+
+func ExampleEngine_NewXid() {
+	_, err := tccEngine.NewXid()
+	fmt.Println(err)
+
+	tccEngine.XidGenerator = func() (string, error) {
+		return "generated-xid", nil
+	}
+	defer func() {
+		tccEngine.XidGenerator = nil
+	}()
+	fmt.Println(tccEngine.NewXid())
+	tcc, err := tccEngine.New(time.Minute, false)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(tcc.Xid())
+	fmt.Println(tcc.Cancel())
+	// Output:
+	// tcc engine tcc-test has no XidGenerator
+	// generated-xid <nil>
+	// generated-xid
+	// <nil>
+}